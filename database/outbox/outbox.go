@@ -0,0 +1,119 @@
+// Package outbox implements the transactional outbox: events are inserted in the
+// transaction of the business write and published afterwards by a Relay, so an
+// event is published if and only if the transaction commits.
+//
+// The outbox table is expected to have the following columns (PostgreSQL shown):
+//
+//	CREATE TABLE outbox (
+//		id            BIGSERIAL PRIMARY KEY,
+//		aggregate_key VARCHAR(255) NOT NULL,
+//		topic         VARCHAR(255) NOT NULL,
+//		headers       TEXT NOT NULL,
+//		payload       BYTEA NOT NULL,
+//		created_at    TIMESTAMP NOT NULL,
+//		published_at  TIMESTAMP NULL
+//	);
+//	CREATE INDEX outbox_pending ON outbox (aggregate_key, id) WHERE published_at IS NULL;
+//
+// Row locking relies on SELECT ... FOR UPDATE SKIP LOCKED (PostgreSQL 9.5+, MySQL 8.0+).
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/sqldb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+const defaultTable = "outbox"
+
+// Option is outbox option.
+type Option func(*Outbox)
+
+// WithTable sets the outbox table name, default is outbox.
+func WithTable(table string) Option {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
+
+// Outbox writes events into the outbox table.
+type Outbox struct {
+	db       *sql.DB
+	postgres bool
+	table    string
+}
+
+// New opens the configured database.
+func New(c *conf.Database, opts ...Option) (*Outbox, error) {
+	db, err := sqldb.Open(c)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithDB(db, c.Driver, opts...), nil
+}
+
+// NewWithDB uses a database handle owned by the caller, driver selects the placeholder style.
+func NewWithDB(db *sql.DB, driver string, opts ...Option) *Outbox {
+	o := &Outbox{
+		db:       db,
+		postgres: sqldb.IsPostgres(driver),
+		table:    defaultTable,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DB returns the database handle.
+func (o *Outbox) DB() *sql.DB {
+	return o.db
+}
+
+// Add inserts msg into the outbox through tx, usually the *sql.Tx of the business write.
+// Events with the same aggregateKey are published in insertion order. Set a message id
+// header (e.g. Nats-Msg-Id) to let the broker drop a redelivery after a relay crash.
+func (o *Outbox) Add(ctx context.Context, tx sqldb.Execer, aggregateKey, topic string, msg *pubsub.Message) error {
+	header := msg.Header
+	if header == nil {
+		header = pubsub.Header{}
+	}
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO " + o.table + " (aggregate_key, topic, headers, payload, created_at) VALUES (?, ?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, o.rebind(query), aggregateKey, topic, string(headers), msg.Data, time.Now().UTC())
+	return err
+}
+
+// AddTx runs fn and the outbox inserts it makes in one transaction.
+func (o *Outbox) AddTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind replaces ? placeholders with $n for postgres drivers.
+func (o *Outbox) rebind(query string) string {
+	if !o.postgres {
+		return query
+	}
+	return sqldb.Rebind(query)
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,17 @@
+package outbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_Rebind(t *testing.T) {
+	query := "DELETE FROM outbox WHERE id IN (" + placeholders(3) + ")"
+
+	mysql := NewWithDB(nil, "mysql")
+	require.Equal(t, "DELETE FROM outbox WHERE id IN (?, ?, ?)", mysql.rebind(query))
+
+	postgres := NewWithDB(nil, "pgx")
+	require.Equal(t, "DELETE FROM outbox WHERE id IN ($1, $2, $3)", postgres.rebind(query))
+}
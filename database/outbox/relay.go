@@ -0,0 +1,247 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+const (
+	defaultInterval       = time.Second
+	defaultBatchSize      = 100
+	defaultPublishTimeout = 5 * time.Second
+)
+
+var _ transport.Server = (*Relay)(nil)
+
+// RelayOption is relay option.
+type RelayOption func(*Relay)
+
+// WithInterval sets how often the relay polls once the outbox is drained.
+func WithInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithBatchSize bounds the number of rows locked per transaction.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithPublishTimeout bounds a single publish.
+func WithPublishTimeout(timeout time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.publishTimeout = timeout
+	}
+}
+
+// KeepPublished marks published rows with published_at instead of deleting them.
+func KeepPublished() RelayOption {
+	return func(r *Relay) {
+		r.keep = true
+	}
+}
+
+// WithRelayLogger with relay logger.
+func WithRelayLogger(logger log.Logger) RelayOption {
+	return func(r *Relay) {
+		r.log = log.NewHelper(logger)
+	}
+}
+
+type row struct {
+	id           int64
+	aggregateKey string
+	topic        string
+	msg          *pubsub.Message
+}
+
+// Relay publishes pending outbox rows. It is a kratos transport.Server so it follows
+// the app lifecycle. Each batch locks the oldest pending row of every aggregate key
+// with FOR UPDATE SKIP LOCKED, so relays running in several pods never publish rows
+// of the same aggregate concurrently or out of order.
+type Relay struct {
+	outbox         *Outbox
+	publisher      pubsub.Publisher
+	log            *log.Helper
+	interval       time.Duration
+	batchSize      int
+	publishTimeout time.Duration
+	keep           bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRelay publishes the rows of o through publisher.
+func NewRelay(o *Outbox, publisher pubsub.Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		outbox:         o,
+		publisher:      publisher,
+		log:            log.NewHelper(log.GetLogger()),
+		interval:       defaultInterval,
+		batchSize:      defaultBatchSize,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return nil
+	}
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.done = make(chan struct{})
+	go r.run(ctx, r.done)
+	r.log.Infof("[OUTBOX] relay started on table %s", r.outbox.table)
+	return nil
+}
+
+// Stop stops polling and waits for the running batch.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel = nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	r.log.Info("[OUTBOX] relay stopped")
+	return nil
+}
+
+func (r *Relay) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		// a batch takes one row per aggregate key, so keep relaying until the outbox
+		// is drained; a failed publish waits for the next tick so a broker outage
+		// does not spin on the same rows
+		for {
+			locked, published, err := r.RelayOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.log.Errorw("msg", "outbox relay failed", "error", err)
+			}
+			if err != nil || locked == 0 || published < locked {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch in a transaction and returns the number of rows it
+// locked and published. The batch stops at the first failed publish, so the row locks
+// are not held through a publish timeout per row while the broker is down; the failed
+// and remaining rows stay pending and are retried by the next batch.
+func (r *Relay) RelayOnce(ctx context.Context) (locked, published int, err error) {
+	tx, err := r.outbox.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := r.lock(ctx, tx)
+	if err != nil {
+		return 0, 0, err
+	}
+	ids := make([]interface{}, 0, len(rows))
+	for _, rw := range rows {
+		if err := r.publish(ctx, rw); err != nil {
+			r.log.Errorw("msg", "outbox publish failed", "id", rw.id, "aggregate_key", rw.aggregateKey, "topic", rw.topic,
+				"skipped", len(rows)-len(ids)-1, "error", err)
+			break
+		}
+		ids = append(ids, rw.id)
+	}
+	if len(ids) > 0 {
+		if err := r.complete(ctx, tx, ids); err != nil {
+			return len(rows), 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return len(rows), 0, err
+	}
+	return len(rows), len(ids), nil
+}
+
+func (r *Relay) lock(ctx context.Context, tx *sql.Tx) ([]row, error) {
+	table := r.outbox.table
+	query := "SELECT id, aggregate_key, topic, headers, payload FROM " + table +
+		" WHERE id IN (SELECT MIN(id) FROM " + table + " WHERE published_at IS NULL GROUP BY aggregate_key)" +
+		" ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED"
+	rs, err := tx.QueryContext(ctx, r.outbox.rebind(query), r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	var rows []row
+	for rs.Next() {
+		var (
+			rw      row
+			headers string
+			payload []byte
+		)
+		if err := rs.Scan(&rw.id, &rw.aggregateKey, &rw.topic, &headers, &payload); err != nil {
+			return nil, err
+		}
+		header := pubsub.Header{}
+		if len(headers) > 0 {
+			if err := json.Unmarshal([]byte(headers), &header); err != nil {
+				return nil, err
+			}
+		}
+		rw.msg = &pubsub.Message{Header: header, Data: payload}
+		rows = append(rows, rw)
+	}
+	return rows, rs.Err()
+}
+
+func (r *Relay) publish(ctx context.Context, rw row) error {
+	ctx, cancel := context.WithTimeout(ctx, r.publishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, rw.topic, rw.msg)
+}
+
+func (r *Relay) complete(ctx context.Context, tx *sql.Tx, ids []interface{}) error {
+	table := r.outbox.table
+	if r.keep {
+		query := "UPDATE " + table + " SET published_at = ? WHERE id IN (" + placeholders(len(ids)) + ")"
+		args := append([]interface{}{time.Now().UTC()}, ids...)
+		_, err := tx.ExecContext(ctx, r.outbox.rebind(query), args...)
+		return err
+	}
+	query := "DELETE FROM " + table + " WHERE id IN (" + placeholders(len(ids)) + ")"
+	_, err := tx.ExecContext(ctx, r.outbox.rebind(query), ids...)
+	return err
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// flakyPublisher fails the publishes listed in fail, counted from 1.
+type flakyPublisher struct {
+	mu    sync.Mutex
+	calls int
+	fail  map[int]bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.fail[p.calls] {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func (p *flakyPublisher) Close() error { return nil }
+
+func (p *flakyPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func expectBatch(mock sqlmock.Sqlmock, ids ...int64) {
+	expectLocked(mock, len(ids), ids...)
+}
+
+// expectLocked expects a batch of size limit locking ids of one aggregate.
+func expectLocked(mock sqlmock.Sqlmock, limit int, ids ...int64) {
+	rows := sqlmock.NewRows([]string{"id", "aggregate_key", "topic", "headers", "payload"})
+	for _, id := range ids {
+		rows.AddRow(id, "order-1", "orders.created", `{"Nats-Msg-Id":["m1"]}`, []byte("{}"))
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, aggregate_key, topic, headers, payload FROM outbox").WithArgs(limit).WillReturnRows(rows)
+}
+
+func TestRelay_StopsAtFirstFailedPublish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	publisher := &flakyPublisher{fail: map[int]bool{2: true}}
+	relay := NewRelay(NewWithDB(db, "pgx"), publisher, WithBatchSize(3))
+
+	expectBatch(mock, 1, 2, 3)
+	mock.ExpectExec(`DELETE FROM outbox WHERE id IN \(\$1\)`).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	locked, published, err := relay.RelayOnce(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 3, locked)
+	require.Equal(t, 1, published)
+	require.Equal(t, 2, publisher.count(), "row 3 is not attempted once row 2 failed")
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_WaitsForTheNextTickAfterAFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	publisher := &flakyPublisher{fail: map[int]bool{1: true}}
+	relay := NewRelay(NewWithDB(db, "pgx"), publisher, WithBatchSize(1), WithInterval(time.Hour))
+
+	// a full batch whose publish fails, the relay must not lock the row again at once
+	expectBatch(mock, 1)
+	mock.ExpectCommit()
+	expectBatch(mock, 1)
+	mock.ExpectExec("DELETE FROM outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.Nil(t, relay.Start(context.Background()))
+	require.Eventually(t, func() bool { return publisher.count() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Nil(t, relay.Stop(context.Background()))
+	require.Equal(t, 1, publisher.count())
+}
+
+func TestRelay_DrainsAnAggregateInOneTick(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	publisher := &flakyPublisher{}
+	relay := NewRelay(NewWithDB(db, "pgx"), publisher, WithBatchSize(10), WithInterval(time.Hour))
+
+	// every batch locks the oldest pending row of the aggregate only
+	for id := int64(1); id <= 3; id++ {
+		expectLocked(mock, 10, id)
+		mock.ExpectExec(`DELETE FROM outbox WHERE id IN \(\$1\)`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectLocked(mock, 10)
+	mock.ExpectCommit()
+
+	require.Nil(t, relay.Start(context.Background()))
+	require.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, time.Millisecond)
+	require.Nil(t, relay.Stop(context.Background()))
+	require.Equal(t, 3, publisher.count())
+}
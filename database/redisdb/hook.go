@@ -0,0 +1,200 @@
+package redisdb
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/nartvt/go-core/database/redisdb"
+
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// HookOption is redis hook option.
+type HookOption func(*hook)
+
+// WithRequests with requests counter, labels are {command, status}.
+func WithRequests(c metrics.Counter) HookOption {
+	return func(h *hook) {
+		h.requests = c
+	}
+}
+
+// WithSeconds with seconds histogram, labels are {command}.
+func WithSeconds(o metrics.Observer) HookOption {
+	return func(h *hook) {
+		h.seconds = o
+	}
+}
+
+// WithTracerProvider with tracer provider, the global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) HookOption {
+	return func(h *hook) {
+		h.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithSlowLog logs commands slower than threshold, logger is usually the one built by log.LogrusConfig.
+func WithSlowLog(logger log.Logger, threshold time.Duration) HookOption {
+	return func(h *hook) {
+		h.log = log.NewHelper(logger)
+		h.slowThreshold = threshold
+	}
+}
+
+var _ redis.Hook = (*hook)(nil)
+
+type hook struct {
+	requests      metrics.Counter
+	seconds       metrics.Observer
+	tracer        trace.Tracer
+	log           *log.Helper
+	slowThreshold time.Duration
+}
+
+// NewHook returns a go-redis hook recording metrics, tracing spans and slow commands.
+func NewHook(opts ...HookOption) redis.Hook {
+	h := &hook{
+		tracer: otel.Tracer(tracerName),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Instrument adds a hook built from opts to the client, clients are not instrumented by default.
+func (r *RedisClient) Instrument(opts ...HookOption) *RedisClient {
+	r.client.AddHook(NewHook(opts...))
+	return r
+}
+
+func (h *hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.tracer.Start(ctx, "redis.dial", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return conn, err
+	}
+}
+
+func (h *hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.tracer.Start(ctx, "redis."+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", cmd.Name()),
+			),
+		)
+		defer span.End()
+
+		start := time.Now()
+		err := next(ctx, cmd)
+		elapsed := time.Since(start)
+
+		h.record(span, cmd.Name(), err, elapsed)
+		if h.log != nil && elapsed >= h.slowThreshold {
+			h.log.WithContext(ctx).Warnw("msg", "redis slow command", "command", cmd.FullName(), "elapsed", elapsed.String())
+		}
+		return err
+	}
+}
+
+func (h *hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+		ctx, span := h.tracer.Start(ctx, "redis.pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", strings.Join(names, " ")),
+				attribute.Int("db.redis.num_cmd", len(cmds)),
+			),
+		)
+		defer span.End()
+
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start)
+
+		for _, cmd := range cmds {
+			if h.requests != nil {
+				h.requests.With(cmd.Name(), status(cmd.Err())).Inc()
+			}
+		}
+		if h.seconds != nil {
+			h.seconds.With("pipeline").Observe(elapsed.Seconds())
+		}
+		if err != nil && err != redis.Nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		if h.log != nil && elapsed >= h.slowThreshold {
+			h.log.WithContext(ctx).Warnw("msg", "redis slow pipeline", "commands", strings.Join(names, " "), "elapsed", elapsed.String())
+		}
+		return err
+	}
+}
+
+func (h *hook) record(span trace.Span, name string, err error, elapsed time.Duration) {
+	if h.requests != nil {
+		h.requests.With(name, status(err)).Inc()
+	}
+	if h.seconds != nil {
+		h.seconds.With(name).Observe(elapsed.Seconds())
+	}
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func status(err error) string {
+	if err != nil && err != redis.Nil {
+		return statusError
+	}
+	return statusOK
+}
+
+// ReportPoolStats sets the client connection pool stats on gauge every interval until ctx is done,
+// the gauge label is {stat}.
+func ReportPoolStats(ctx context.Context, client redis.UniversalClient, gauge metrics.Gauge, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats := client.PoolStats()
+		gauge.With("hits").Set(float64(stats.Hits))
+		gauge.With("misses").Set(float64(stats.Misses))
+		gauge.With("timeouts").Set(float64(stats.Timeouts))
+		gauge.With("total_conns").Set(float64(stats.TotalConns))
+		gauge.With("idle_conns").Set(float64(stats.IdleConns))
+		gauge.With("stale_conns").Set(float64(stats.StaleConns))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
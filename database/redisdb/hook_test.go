@@ -0,0 +1,105 @@
+package redisdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recorder implements the kratos metrics interfaces, values are keyed by the joined labels.
+type recorder struct {
+	mu     sync.Mutex
+	values map[string]float64
+	lvs    []string
+}
+
+func newRecorder() *recorder {
+	return &recorder{values: map[string]float64{}}
+}
+
+func (r *recorder) With(lvs ...string) metrics.Counter {
+	return &recorder{values: r.values, lvs: lvs}
+}
+
+func (r *recorder) key() string {
+	key := ""
+	for _, lv := range r.lvs {
+		key += lv + "/"
+	}
+	return key
+}
+
+func (r *recorder) Inc()              { r.Add(1) }
+func (r *recorder) Add(delta float64) { r.values[r.key()] += delta }
+
+type gauge struct{ *recorder }
+
+func (g gauge) With(lvs ...string) metrics.Gauge { return gauge{&recorder{values: g.values, lvs: lvs}} }
+func (g gauge) Set(value float64)                { g.values[g.key()] = value }
+func (g gauge) Sub(delta float64)                { g.Add(-delta) }
+
+type observer struct{ *recorder }
+
+func (o observer) With(lvs ...string) metrics.Observer {
+	return observer{&recorder{values: o.values, lvs: lvs}}
+}
+func (o observer) Observe(float64) { o.Add(1) }
+
+func TestHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	spans := tracetest.NewSpanRecorder()
+	requests, seconds := newRecorder(), newRecorder()
+	client := WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()})).Instrument(
+		WithRequests(requests),
+		WithSeconds(observer{seconds}),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+	)
+	ctx := context.Background()
+
+	_, err := client.Set(ctx, "k", "v", 0)
+	require.Nil(t, err)
+	_, err = client.Get(ctx, "missing")
+	require.Equal(t, redis.Nil, err)
+	require.NotNil(t, client.GetClient().Incr(ctx, "k").Err())
+	pipe := client.GetClient().Pipeline()
+	pipe.Get(ctx, "k")
+	pipe.Get(ctx, "k")
+	_, err = pipe.Exec(ctx)
+	require.Nil(t, err)
+
+	require.Equal(t, float64(1), requests.values["set/ok/"])
+	require.Equal(t, float64(3), requests.values["get/ok/"], "a miss is not an error")
+	require.Equal(t, float64(1), requests.values["incr/error/"])
+	require.Equal(t, float64(1), seconds.values["pipeline/"])
+
+	ended := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans.Ended() {
+		ended[span.Name()] = span
+	}
+	require.Contains(t, ended, "redis.set")
+	require.Contains(t, ended, "redis.pipeline")
+	require.Equal(t, codes.Unset, ended["redis.get"].Status().Code)
+	require.Equal(t, codes.Error, ended["redis.incr"].Status().Code)
+}
+
+func TestReportPoolStats(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	require.Nil(t, client.Ping(context.Background()).Err())
+
+	g := gauge{newRecorder()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ReportPoolStats(ctx, client, g, time.Hour)
+	require.Equal(t, float64(1), g.values["total_conns/"])
+	require.Contains(t, g.values, "timeouts/")
+}
@@ -0,0 +1,100 @@
+package redisdb
+
+import (
+	"strconv"
+	"strings"
+)
+
+const keySeparator = ":"
+
+// KeyBuilder builds namespaced and versioned redis keys in the form
+// <service>:v<version>[:<tenant>]:<parts...>.
+// Bumping the version makes every key of the family unreachable at once,
+// the old keys simply expire with their ttl.
+// A nil *KeyBuilder joins the parts as they are, so callers can use it unconditionally.
+type KeyBuilder struct {
+	prefix string
+}
+
+func NewKeyBuilder(service string, version int) *KeyBuilder {
+	return &KeyBuilder{prefix: service + keySeparator + "v" + strconv.Itoa(version)}
+}
+
+// Tenant returns a builder scoped to tenant.
+func (k *KeyBuilder) Tenant(tenant string) *KeyBuilder {
+	return &KeyBuilder{prefix: k.join(tenant)}
+}
+
+// Family returns a builder for a cache family with its own version,
+// so a single family can be invalidated without touching the others.
+func (k *KeyBuilder) Family(name string, version int) *KeyBuilder {
+	return &KeyBuilder{prefix: k.join(name, "v"+strconv.Itoa(version))}
+}
+
+// Prefix returns the namespace shared by every key of the builder.
+func (k *KeyBuilder) Prefix() string {
+	if k == nil {
+		return ""
+	}
+	return k.prefix
+}
+
+// Key joins parts under the builder namespace.
+func (k *KeyBuilder) Key(parts ...string) string {
+	return k.join(parts...)
+}
+
+// Tagged builds a key with a {tag} segment, keys sharing a tag land on the same cluster slot.
+func (k *KeyBuilder) Tagged(tag string, parts ...string) string {
+	return k.join(append([]string{"{" + tag + "}"}, parts...)...)
+}
+
+// Pattern builds a SCAN pattern matching every key under parts, the namespace
+// and parts are matched literally.
+func (k *KeyBuilder) Pattern(parts ...string) string {
+	escaped := make([]string, 0, len(parts)+1)
+	for _, part := range parts {
+		escaped = append(escaped, EscapePattern(part))
+	}
+	return k.Match(strings.Join(append(escaped, "*"), keySeparator))
+}
+
+// Match scopes a caller SCAN pattern to the builder namespace, glob characters
+// of the namespace are escaped so it never matches the keys of another one.
+func (k *KeyBuilder) Match(pattern string) string {
+	if k == nil || len(k.prefix) == 0 {
+		return pattern
+	}
+	return EscapePattern(k.prefix) + keySeparator + pattern
+}
+
+// EscapePattern escapes the glob characters of s for a SCAN or KEYS pattern.
+func EscapePattern(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Trim strips the builder namespace from a full key.
+func (k *KeyBuilder) Trim(key string) string {
+	if k == nil || len(k.prefix) == 0 {
+		return key
+	}
+	return strings.TrimPrefix(key, k.prefix+keySeparator)
+}
+
+func (k *KeyBuilder) join(parts ...string) string {
+	if k == nil || len(k.prefix) == 0 {
+		return strings.Join(parts, keySeparator)
+	}
+	return k.prefix + keySeparator + strings.Join(parts, keySeparator)
+}
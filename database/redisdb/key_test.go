@@ -0,0 +1,35 @@
+package redisdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBuilder(t *testing.T) {
+	keys := NewKeyBuilder("order", 2)
+	require.Equal(t, "order:v2:item:1", keys.Key("item", "1"))
+	require.Equal(t, "order:v2:acme:item:1", keys.Tenant("acme").Key("item", "1"))
+	require.Equal(t, "order:v2:price:v5:1", keys.Family("price", 5).Key("1"))
+	require.Equal(t, "order:v2:{user1}:cart", keys.Tagged("user1", "cart"))
+	require.Equal(t, "order:v2:item:*", keys.Pattern("item"))
+	require.Equal(t, "item:1", keys.Trim(keys.Key("item", "1")))
+	require.NotEqual(t, keys.Key("item"), NewKeyBuilder("order", 3).Key("item"))
+}
+
+func TestKeyBuilder_Nil(t *testing.T) {
+	var keys *KeyBuilder
+	require.Equal(t, "item:1", keys.Key("item", "1"))
+	require.Equal(t, "item:1", keys.Trim("item:1"))
+	require.Equal(t, "acme:item", keys.Tenant("acme").Key("item"))
+	require.Equal(t, "item:*", keys.Pattern("item"))
+	require.Equal(t, "item:?", keys.Match("item:?"))
+}
+
+func TestKeyBuilder_EscapesPatterns(t *testing.T) {
+	keys := NewKeyBuilder("shop*", 1).Tenant("a[b]")
+	require.Equal(t, `shop\*:v1:a\[b\]:item:*`, keys.Pattern("item"))
+	require.Equal(t, `shop\*:v1:a\[b\]:item:?`, keys.Match("item:?"))
+	require.Equal(t, `shop\*:v1:a\[b\]:a\?b:*`, keys.Pattern("a?b"))
+	require.Equal(t, `a\\b`, EscapePattern(`a\b`))
+}
@@ -0,0 +1,457 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+var (
+	ErrWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	ErrNotInteger = errors.New("ERR value is not an integer or out of range")
+	ErrNoSuchKey  = errors.New("ERR no such key")
+)
+
+// Clock tells the store the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FakeClock is a manually driven Clock for tests.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Option is memory store option.
+type Option func(*Store)
+
+// WithClock replaces the system clock, use a FakeClock to expire keys in tests.
+func WithClock(clock Clock) Option {
+	return func(s *Store) {
+		s.clock = clock
+	}
+}
+
+type item struct {
+	str      string
+	hash     map[string]string
+	expireAt time.Time
+}
+
+func (i *item) isHash() bool {
+	return i.hash != nil
+}
+
+var _ redisdb.Store = (*Store)(nil)
+
+// Store is an in-memory redisdb.Store for unit tests.
+type Store struct {
+	mu    sync.Mutex
+	clock Clock
+	items map[string]*item
+}
+
+func New(opts ...Option) *Store {
+	s := &Store{
+		clock: systemClock{},
+		items: make(map[string]*item),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FlushAll removes every key.
+func (s *Store) FlushAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]*item)
+}
+
+// lookup returns the live item of key, expired items are removed. s.mu must be held.
+func (s *Store) lookup(key string) *item {
+	it, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.expireAt.IsZero() && !s.clock.Now().Before(it.expireAt) {
+		delete(s.items, key)
+		return nil
+	}
+	return it
+}
+
+func (s *Store) expireAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return s.clock.Now().Add(expiration)
+}
+
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return "", redis.Nil
+	}
+	if it.isHash() {
+		return "", ErrWrongType
+	}
+	return it.str, nil
+}
+
+func (s *Store) Set(ctx context.Context, key, val string, expiredInSec int32) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// like RedisClient.Set, zero or a negative ttl stores the key without expiry
+	s.items[key] = &item{str: val, expireAt: s.expireAt(time.Duration(expiredInSec) * time.Second)}
+	return "OK", nil
+}
+
+func (s *Store) SetNX(ctx context.Context, key, val string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lookup(key) != nil {
+		return false, nil
+	}
+	s.items[key] = &item{str: val, expireAt: s.expireAt(expiration)}
+	return true, nil
+}
+
+func (s *Store) Del(ctx context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if s.lookup(key) != nil {
+			delete(s.items, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) Exists(ctx context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if s.lookup(key) != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return false, nil
+	}
+	if expiration <= 0 {
+		delete(s.items, key)
+		return true, nil
+	}
+	it.expireAt = s.expireAt(expiration)
+	return true, nil
+}
+
+// TTL follows go-redis, -2ns when the key does not exist and -1ns when it has no expiry.
+func (s *Store) TTL(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return -2, nil
+	}
+	if it.expireAt.IsZero() {
+		return -1, nil
+	}
+	return it.expireAt.Sub(s.clock.Now()).Truncate(time.Second), nil
+}
+
+func (s *Store) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		it = &item{str: "0"}
+		s.items[key] = it
+	}
+	if it.isHash() {
+		return 0, ErrWrongType
+	}
+	n, err := strconv.ParseInt(it.str, 10, 64)
+	if err != nil {
+		return 0, ErrNotInteger
+	}
+	n += value
+	it.str = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (s *Store) HGet(ctx context.Context, key, field string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return "", redis.Nil
+	}
+	if !it.isHash() {
+		return "", ErrWrongType
+	}
+	val, ok := it.hash[field]
+	if !ok {
+		return "", redis.Nil
+	}
+	return val, nil
+}
+
+func (s *Store) HSet(ctx context.Context, key string, fields map[string]string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		it = &item{hash: make(map[string]string)}
+		s.items[key] = it
+	}
+	if !it.isHash() {
+		return 0, ErrWrongType
+	}
+	var added int64
+	for field, val := range fields {
+		if _, ok := it.hash[field]; !ok {
+			added++
+		}
+		it.hash[field] = val
+	}
+	return added, nil
+}
+
+func (s *Store) HSetNX(ctx context.Context, key, field, val string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		it = &item{hash: make(map[string]string)}
+		s.items[key] = it
+	}
+	if !it.isHash() {
+		return false, ErrWrongType
+	}
+	if _, ok := it.hash[field]; ok {
+		return false, nil
+	}
+	it.hash[field] = val
+	return true, nil
+}
+
+func (s *Store) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[string]string)
+	it := s.lookup(key)
+	if it == nil {
+		return result, nil
+	}
+	if !it.isHash() {
+		return nil, ErrWrongType
+	}
+	for field, val := range it.hash {
+		result[field] = val
+	}
+	return result, nil
+}
+
+func (s *Store) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return 0, nil
+	}
+	if !it.isHash() {
+		return 0, ErrWrongType
+	}
+	var n int64
+	for _, field := range fields {
+		if _, ok := it.hash[field]; ok {
+			delete(it.hash, field)
+			n++
+		}
+	}
+	if len(it.hash) == 0 {
+		delete(s.items, key)
+	}
+	return n, nil
+}
+
+// MGet returns nil for missing keys and for keys that do not hold a string, like redis.
+func (s *Store) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if it := s.lookup(key); it != nil && !it.isHash() {
+			result[i] = it.str
+		}
+	}
+	return result, nil
+}
+
+// Scan returns every matching key in one sorted page, the next cursor is always 0.
+func (s *Store) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0)
+	for key := range s.items {
+		if s.lookup(key) == nil {
+			continue
+		}
+		if len(match) == 0 || matchPattern(match, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, 0, nil
+}
+
+func (s *Store) Rename(ctx context.Context, key, newkey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	if it == nil {
+		return ErrNoSuchKey
+	}
+	delete(s.items, key)
+	s.items[newkey] = it
+	return nil
+}
+
+func (s *Store) Type(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it := s.lookup(key)
+	switch {
+	case it == nil:
+		return "none", nil
+	case it.isHash():
+		return "hash", nil
+	default:
+		return "string", nil
+	}
+}
+
+// matchPattern reports whether key matches a redis glob pattern: *, ?, [abc],
+// [^abc], [a-z] and backslash escapes.
+func matchPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchPattern(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			rest, ok := matchClass(pattern[1:], key[0])
+			if !ok {
+				return false
+			}
+			pattern = rest
+			key = key[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			key = key[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(key) == 0
+}
+
+// matchClass matches c against the class following a '[' and returns the
+// pattern after the closing ']'.
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return pattern, matched != negate
+}
@@ -0,0 +1,168 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+func TestStore_StringTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	s := New(WithClock(clock))
+
+	_, err := s.Get(ctx, "k")
+	require.Equal(t, redis.Nil, err)
+
+	_, err = s.Set(ctx, "k", "v", 10)
+	require.Nil(t, err)
+	ok, err := s.SetNX(ctx, "k", "other", 0)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	ttl, err := s.TTL(ctx, "k")
+	require.Nil(t, err)
+	require.Equal(t, 10*time.Second, ttl)
+
+	clock.Advance(9 * time.Second)
+	val, err := s.Get(ctx, "k")
+	require.Nil(t, err)
+	require.Equal(t, "v", val)
+
+	clock.Advance(time.Second)
+	_, err = s.Get(ctx, "k")
+	require.Equal(t, redis.Nil, err)
+	ttl, _ = s.TTL(ctx, "k")
+	require.Equal(t, time.Duration(-2), ttl)
+}
+
+func TestStore_Hash(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	added, err := s.HSet(ctx, "h", map[string]string{"a": "1", "b": "2"})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), added)
+	added, _ = s.HSet(ctx, "h", map[string]string{"a": "3"})
+	require.Equal(t, int64(0), added)
+
+	val, err := s.HGet(ctx, "h", "a")
+	require.Nil(t, err)
+	require.Equal(t, "3", val)
+	_, err = s.Get(ctx, "h")
+	require.Equal(t, ErrWrongType, err)
+
+	n, _ := s.HDel(ctx, "h", "a", "b")
+	require.Equal(t, int64(2), n)
+	n, _ = s.Exists(ctx, "h")
+	require.Equal(t, int64(0), n)
+}
+
+func TestStore_IncrBy(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	n, err := s.IncrBy(ctx, "c", 5)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), n)
+	n, _ = s.IncrBy(ctx, "c", -2)
+	require.Equal(t, int64(3), n)
+
+	_, _ = s.Set(ctx, "s", "abc", 0)
+	_, err = s.IncrBy(ctx, "s", 1)
+	require.Equal(t, ErrNotInteger, err)
+}
+
+// TestStore_MatchesRedisClient runs the same calls against the fake and a RedisClient.
+func TestStore_MatchesRedisClient(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	stores := map[string]redisdb.Store{
+		"memstore": New(),
+		"redis":    redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Set(ctx, "k", "v", 10)
+			require.Nil(t, err)
+			_, err = s.Set(ctx, "k", "v2", -1)
+			require.Nil(t, err)
+			ttl, err := s.TTL(ctx, "k")
+			require.Nil(t, err)
+			require.Equal(t, time.Duration(-1), ttl, "a negative ttl removes the expiry")
+
+			n, err := s.IncrBy(ctx, "n", 2)
+			require.Nil(t, err)
+			require.Equal(t, int64(2), n)
+			_, err = s.IncrBy(ctx, "k", 1)
+			require.NotNil(t, err)
+
+			ok, err := s.SetNX(ctx, "n", "x", time.Minute)
+			require.Nil(t, err)
+			require.False(t, ok)
+			_, err = s.HGet(ctx, "h", "f")
+			require.Equal(t, redis.Nil, err)
+			ok, err = s.HSetNX(ctx, "h", "f", "1")
+			require.Nil(t, err)
+			require.True(t, ok)
+			ok, err = s.HSetNX(ctx, "h", "f", "2")
+			require.Nil(t, err)
+			require.False(t, ok)
+			val, err := s.HGet(ctx, "h", "f")
+			require.Nil(t, err)
+			require.Equal(t, "1", val)
+			deleted, err := s.Del(ctx, "k", "n", "h", "missing")
+			require.Nil(t, err)
+			require.Equal(t, int64(3), deleted)
+		})
+	}
+}
+
+func TestStore_ScanRenameType(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	stores := map[string]redisdb.Store{
+		"memstore": New(),
+		"redis":    redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, _ = s.Set(ctx, "item:1", "a", 0)
+			_, _ = s.Set(ctx, "item:2", "b", 0)
+			_, _ = s.Set(ctx, "item*", "c", 0)
+			_, _ = s.HSet(ctx, "order:1", map[string]string{"f": "v"})
+
+			vals, err := s.MGet(ctx, "item:1", "missing", "order:1")
+			require.Nil(t, err)
+			require.Equal(t, []interface{}{"a", nil, nil}, vals)
+
+			keys, cursor, err := s.Scan(ctx, 0, `item\*`, 100)
+			require.Nil(t, err)
+			require.Zero(t, cursor)
+			require.Equal(t, []string{"item*"}, keys)
+			keys, _, err = s.Scan(ctx, 0, "item:[12]", 100)
+			require.Nil(t, err)
+			require.ElementsMatch(t, []string{"item:1", "item:2"}, keys)
+
+			typ, err := s.Type(ctx, "order:1")
+			require.Nil(t, err)
+			require.Equal(t, "hash", typ)
+			typ, _ = s.Type(ctx, "item:1")
+			require.Equal(t, "string", typ)
+
+			require.Nil(t, s.Rename(ctx, "item:1", "item:3"))
+			typ, _ = s.Type(ctx, "item:1")
+			require.Equal(t, "none", typ)
+			val, err := s.Get(ctx, "item:3")
+			require.Nil(t, err)
+			require.Equal(t, "a", val)
+			require.NotNil(t, s.Rename(ctx, "missing", "other"))
+		})
+	}
+}
@@ -11,8 +11,11 @@ import (
 
 type RedisClient struct {
 	client *redis.Client
+	keys   *KeyBuilder
 }
 
+// NewRedisClient creates a client without instrumentation, metrics, tracing and the
+// slow log are opt-in with Instrument or a hook passed to redis_helper.InitRedis.
 func NewRedisClient(rediConf *conf.Redis) *RedisClient {
 	if len(rediConf.Username) == 0 {
 		rediConf.Username = "default"
@@ -37,10 +40,21 @@ func (r *RedisClient) GetClient() *redis.Client {
 	return r.client
 }
 
+// WithKeyBuilder namespaces every key used through the client and the packages built on it.
+func (r *RedisClient) WithKeyBuilder(keys *KeyBuilder) *RedisClient {
+	r.keys = keys
+	return r
+}
+
+// KeyBuilder returns the client key builder, it may be nil.
+func (r *RedisClient) KeyBuilder() *KeyBuilder {
+	return r.keys
+}
+
 func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	return r.client.Get(ctx, r.keys.Key(key)).Result()
 }
 
 func (r *RedisClient) Set(ctx context.Context, key, val string, expiredInSec int32) (string, error) {
-	return r.client.Set(ctx, key, val, time.Duration(expiredInSec)*time.Second).Result()
+	return r.client.Set(ctx, r.keys.Key(key), val, time.Duration(expiredInSec)*time.Second).Result()
 }
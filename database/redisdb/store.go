@@ -0,0 +1,120 @@
+package redisdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the set of redis operations go-core exposes. It is implemented by
+// *RedisClient and by memstore.Store, so business logic can depend on Store and
+// be unit tested without a redis server. Missing keys are reported with redis.Nil.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+	MGet(ctx context.Context, keys ...string) ([]interface{}, error)
+	Set(ctx context.Context, key, val string, expiredInSec int32) (string, error)
+	SetNX(ctx context.Context, key, val string, expiration time.Duration) (bool, error)
+	Del(ctx context.Context, keys ...string) (int64, error)
+	Exists(ctx context.Context, keys ...string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+	HGet(ctx context.Context, key, field string) (string, error)
+	HSet(ctx context.Context, key string, fields map[string]string) (int64, error)
+	HSetNX(ctx context.Context, key, field, val string) (bool, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error)
+	Rename(ctx context.Context, key, newkey string) error
+	Type(ctx context.Context, key string) (string, error)
+}
+
+var _ Store = (*RedisClient)(nil)
+
+// WrapClient builds a RedisClient on an existing connection.
+func WrapClient(client *redis.Client) *RedisClient {
+	return &RedisClient{client: client}
+}
+
+func (r *RedisClient) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	return r.client.MGet(ctx, r.keyList(keys)...).Result()
+}
+
+func (r *RedisClient) SetNX(ctx context.Context, key, val string, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, r.keys.Key(key), val, expiration).Result()
+}
+
+func (r *RedisClient) Del(ctx context.Context, keys ...string) (int64, error) {
+	return r.client.Del(ctx, r.keyList(keys)...).Result()
+}
+
+func (r *RedisClient) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return r.client.Exists(ctx, r.keyList(keys)...).Result()
+}
+
+func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return r.client.Expire(ctx, r.keys.Key(key), expiration).Result()
+}
+
+func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.client.TTL(ctx, r.keys.Key(key)).Result()
+}
+
+func (r *RedisClient) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	return r.client.IncrBy(ctx, r.keys.Key(key), value).Result()
+}
+
+func (r *RedisClient) HGet(ctx context.Context, key, field string) (string, error) {
+	return r.client.HGet(ctx, r.keys.Key(key), field).Result()
+}
+
+func (r *RedisClient) HSet(ctx context.Context, key string, fields map[string]string) (int64, error) {
+	return r.client.HSet(ctx, r.keys.Key(key), fields).Result()
+}
+
+func (r *RedisClient) HSetNX(ctx context.Context, key, field, val string) (bool, error) {
+	return r.client.HSetNX(ctx, r.keys.Key(key), field, val).Result()
+}
+
+func (r *RedisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r.client.HGetAll(ctx, r.keys.Key(key)).Result()
+}
+
+func (r *RedisClient) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return r.client.HDel(ctx, r.keys.Key(key), fields...).Result()
+}
+
+// Scan matches keys inside the builder namespace and returns them without it.
+func (r *RedisClient) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	if len(match) == 0 {
+		match = "*"
+	}
+	keys, next, err := r.client.Scan(ctx, cursor, r.keys.Match(match), count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, k := range keys {
+		keys[i] = r.keys.Trim(k)
+	}
+	return keys, next, nil
+}
+
+func (r *RedisClient) Rename(ctx context.Context, key, newkey string) error {
+	return r.client.Rename(ctx, r.keys.Key(key), r.keys.Key(newkey)).Err()
+}
+
+func (r *RedisClient) Type(ctx context.Context, key string) (string, error) {
+	return r.client.Type(ctx, r.keys.Key(key)).Result()
+}
+
+func (r *RedisClient) keyList(keys []string) []string {
+	if r.keys == nil {
+		return keys
+	}
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = r.keys.Key(k)
+	}
+	return result
+}
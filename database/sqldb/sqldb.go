@@ -0,0 +1,61 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nartvt/go-core/conf"
+)
+
+// Open opens the configured database and checks the connection. The driver
+// package (e.g. github.com/jackc/pgx/v5/stdlib) must be imported by the caller.
+func Open(c *conf.Database) (*sql.DB, error) {
+	if c == nil || len(c.Driver) == 0 {
+		return nil, errors.New("sqldb: database driver is not configured")
+	}
+	db, err := sql.Open(c.Driver, c.Source)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Execer is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// IsPostgres reports whether driver uses $n placeholders.
+func IsPostgres(driver string) bool {
+	switch driver {
+	case "postgres", "pgx", "pgx/v5", "cloudsqlpostgres":
+		return true
+	}
+	return false
+}
+
+// Rebind replaces ? placeholders with $n, use it for postgres drivers.
+func Rebind(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
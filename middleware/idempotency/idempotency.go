@@ -0,0 +1,226 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nuid"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/middleware/jwt"
+)
+
+const (
+	// idempotencyKey is the default header or metadata holding the key.
+	idempotencyKey = "Idempotency-Key"
+
+	// reason holds the error reason.
+	reason = "IDEMPOTENCY_CONFLICT"
+
+	stateInFlight = "in_flight"
+	stateDone     = "done"
+)
+
+var (
+	ErrInFlight         = errors.Conflict(reason, "A request with the same idempotency key is in progress")
+	ErrAlreadyProcessed = errors.Conflict(reason, "A request with the same idempotency key was already processed")
+	ErrKeyReused        = errors.New(http.StatusUnprocessableEntity, reason, "The idempotency key was used with a different request")
+)
+
+var (
+	// releaseScript deletes the in-flight marker only when it is still the one of the request.
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// completeScript stores the reply unless another request took the key after the marker expired.
+	completeScript = redis.NewScript(`
+local v = redis.call("GET", KEYS[1])
+if v == false or v == ARGV[1] then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return false`)
+)
+
+// Option is idempotency option.
+type Option func(*options)
+
+type options struct {
+	header  string
+	prefix  string
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// WithHeader changes the header or metadata key holding the idempotency key.
+func WithHeader(header string) Option {
+	return func(o *options) {
+		o.header = header
+	}
+}
+
+// WithKeyPrefix changes the redis key prefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithTTL sets how long a final response is kept for replays.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithLockTTL sets how long the in-flight marker lives, it should be longer than the request timeout.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.lockTTL = ttl
+	}
+}
+
+type record struct {
+	State       string `json:"state"`
+	Token       string `json:"token,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Reply       []byte `json:"reply,omitempty"`
+}
+
+// Server is a server idempotency middleware.
+// The first request carrying a key runs the handler and stores its reply,
+// replays get the stored reply back and concurrent duplicates get ErrInFlight.
+// A key reused with another operation or request body gets ErrKeyReused (422).
+//
+// Only proto.Message replies are stored: the replay of a request whose handler
+// returned another type, such as a struct encoded with the json codec, gets
+// ErrAlreadyProcessed (409) instead of the reply.
+func Server(client *redisdb.RedisClient, opts ...Option) middleware.Middleware {
+	o := &options{
+		header:  idempotencyKey,
+		prefix:  "idempotency:",
+		ttl:     24 * time.Hour,
+		lockTTL: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	rdb := client.GetClient()
+	keys := client.KeyBuilder()
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			idemKey := tr.RequestHeader().Get(o.header)
+			if len(idemKey) == 0 {
+				return handler(ctx, req)
+			}
+
+			key := o.prefix + tr.Operation() + ":"
+			if userId, _ := jwt.GetUserId(ctx); len(userId) > 0 {
+				key += userId + ":"
+			}
+			key = keys.Key(key + idemKey)
+
+			sum, err := fingerprint(tr.Operation(), req)
+			if err != nil {
+				return nil, err
+			}
+			inFlight, _ := json.Marshal(&record{State: stateInFlight, Token: nuid.Next(), Fingerprint: sum})
+			acquired, err := rdb.SetNX(ctx, key, inFlight, o.lockTTL).Result()
+			if err != nil {
+				return nil, err
+			}
+			if !acquired {
+				return replay(ctx, rdb, key, sum)
+			}
+
+			reply, err := handler(ctx, req)
+			if err != nil {
+				// let the client retry with the same key
+				releaseScript.Run(context.Background(), rdb, []string{key}, inFlight)
+				return nil, err
+			}
+
+			done := &record{State: stateDone, Fingerprint: sum}
+			if msg, ok := reply.(proto.Message); ok {
+				if anyReply, err := anypb.New(msg); err == nil {
+					done.Reply, _ = proto.Marshal(anyReply)
+				}
+			}
+			data, err := json.Marshal(done)
+			if err != nil {
+				return reply, nil
+			}
+			completeScript.Run(context.Background(), rdb, []string{key}, inFlight, data, o.ttl.Milliseconds())
+			return reply, nil
+		}
+	}
+}
+
+// fingerprint hashes the operation and the request, proto requests are marshaled
+// deterministically and other requests json encoded.
+func fingerprint(operation string, req interface{}) (string, error) {
+	var (
+		body []byte
+		err  error
+	)
+	if msg, ok := req.(proto.Message); ok {
+		body, err = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	} else {
+		body, err = json.Marshal(req)
+	}
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(operation))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func replay(ctx context.Context, rdb *redis.Client, key, sum string) (interface{}, error) {
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		// the owner failed and released the key in between
+		return nil, ErrInFlight
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := &record{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+	if rec.Fingerprint != sum {
+		return nil, ErrKeyReused
+	}
+	if rec.State != stateDone {
+		return nil, ErrInFlight
+	}
+	if len(rec.Reply) == 0 {
+		return nil, ErrAlreadyProcessed
+	}
+
+	anyReply := &anypb.Any{}
+	if err := proto.Unmarshal(rec.Reply, anyReply); err != nil {
+		return nil, err
+	}
+	return anyReply.UnmarshalNew()
+}
@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Add(key string, value string) { http.Header(hc).Add(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	header headerCarrier
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (tr *testTransport) Endpoint() string                { return "" }
+func (tr *testTransport) Operation() string               { return "/orders.v1.Orders/Create" }
+func (tr *testTransport) RequestHeader() transport.Header { return tr.header }
+func (tr *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+func newContext(key string) context.Context {
+	hc := headerCarrier{}
+	hc.Set(idempotencyKey, key)
+	return transport.NewServerContext(context.Background(), &testTransport{header: hc})
+}
+
+func newClient(t *testing.T) (*miniredis.Miniredis, *redisdb.RedisClient) {
+	mr := miniredis.RunT(t)
+	return mr, redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+}
+
+func TestServer_Replay(t *testing.T) {
+	_, client := newClient(t)
+	var calls int32
+	h := Server(client)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return wrapperspb.String(fmt.Sprintf("%s-%d", req.(*wrapperspb.StringValue).Value, n)), nil
+	})
+
+	reply, err := h(newContext("k1"), wrapperspb.String("order"))
+	require.Nil(t, err)
+	require.Equal(t, "order-1", reply.(*wrapperspb.StringValue).Value)
+
+	reply, err = h(newContext("k1"), wrapperspb.String("order"))
+	require.Nil(t, err)
+	require.True(t, proto.Equal(wrapperspb.String("order-1"), reply.(proto.Message)))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err = h(newContext("k1"), wrapperspb.String("other order"))
+	require.Equal(t, http.StatusUnprocessableEntity, int(errors.FromError(err).Code))
+
+	reply, err = h(newContext("k2"), wrapperspb.String("order"))
+	require.Nil(t, err)
+	require.Equal(t, "order-2", reply.(*wrapperspb.StringValue).Value)
+}
+
+func TestServer_InFlightAndFailure(t *testing.T) {
+	_, client := newClient(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Server(client)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, errors.InternalServer("FAILED", "failed")
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := h(newContext("k1"), wrapperspb.String("order"))
+		done <- err
+	}()
+	<-started
+	_, err := h(newContext("k1"), wrapperspb.String("order"))
+	require.Equal(t, ErrInFlight, err)
+	close(release)
+	require.NotNil(t, <-done)
+
+	// a failed request releases the key for a retry
+	reply, err := Server(client)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return wrapperspb.Bool(true), nil
+	})(newContext("k1"), wrapperspb.String("order"))
+	require.Nil(t, err)
+	require.True(t, reply.(*wrapperspb.BoolValue).Value)
+}
+
+func TestServer_ExpiredLockIsNotReleasedByOwner(t *testing.T) {
+	mr, client := newClient(t)
+	release := make(chan struct{})
+	slow := Server(client, WithLockTTL(time.Second))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-release
+		return nil, errors.InternalServer("FAILED", "failed")
+	})
+	done := make(chan struct{})
+	go func() {
+		_, _ = slow(newContext("k1"), wrapperspb.String("order"))
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(mr.Keys()) == 1 }, time.Second, time.Millisecond)
+
+	// the lock expires and a retry takes the key while the first request still runs
+	mr.FastForward(2 * time.Second)
+	retry := Server(client)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(release)
+		<-done
+		return wrapperspb.Bool(true), nil
+	})
+	_, err := retry(newContext("k1"), wrapperspb.String("order"))
+	require.Nil(t, err)
+
+	_, err = slow(newContext("k1"), wrapperspb.String("order"))
+	require.Nil(t, err, "the reply of the retry is kept")
+}
+
+func TestServer_NonProtoReply(t *testing.T) {
+	_, client := newClient(t)
+	h := Server(client)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return map[string]string{"id": "1"}, nil
+	})
+	_, err := h(newContext("k1"), map[string]string{"name": "order"})
+	require.Nil(t, err)
+	_, err = h(newContext("k1"), map[string]string{"name": "order"})
+	require.Equal(t, ErrAlreadyProcessed, err)
+}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"os"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"google.golang.org/grpc/metadata"
 
 	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
 )
@@ -32,6 +34,9 @@ const (
 
 	// reason holds the error reason.
 	reason String = "UNAUTHORIZED"
+
+	// sessionIdClaim holds the claim name of the server side session id.
+	sessionIdClaim = "sid"
 )
 
 var (
@@ -45,8 +50,15 @@ var (
 	ErrNeedTokenProvider      = errors.Unauthorized(string(reason), "Token provider is missing")
 	ErrSignToken              = errors.Unauthorized(string(reason), "Can not sign token.Is the key correct?")
 	ErrGetKey                 = errors.Unauthorized(string(reason), "Can not get key while signing token")
+	ErrSessionRevoked         = errors.Unauthorized(string(reason), "Session has been revoked")
+	ErrSessionUnavailable     = errors.ServiceUnavailable("SESSION_UNAVAILABLE", "Session can not be checked")
+	ErrKeyConfig              = errors.InternalServer("JWT_KEY_INVALID", "JWT keys can not be loaded")
 )
 
+// SessionValidator reports whether the session referenced by the sid claim is still active.
+// An error rejects the request with ErrSessionUnavailable rather than ErrSessionRevoked.
+type SessionValidator func(ctx context.Context, sid string) (bool, error)
+
 // Option is jwt option.
 type Option func(*options)
 
@@ -60,6 +72,13 @@ type options struct {
 	tokenHeader   map[string]interface{}
 	key           string
 	keyFunc       jwtlib.Keyfunc
+	sessionCheck  SessionValidator
+	secretSet     bool
+	publicKeys    map[string]crypto.PublicKey
+	privateKey    crypto.Signer
+	keyErr        error
+	// algs are the algs of the accepted tokens
+	algs map[string]bool
 }
 
 func WithRequired(required bool) Option {
@@ -105,9 +124,12 @@ func WithTokenHeader(header map[string]interface{}) Option {
 	}
 }
 
+// WithSecretKey sets the HMAC secret, default is ENV_JWT_SECRET. Once public keys are
+// set, HMAC tokens are only accepted when the secret is set with this option.
 func WithSecretKey(key string) Option {
 	return func(o *options) {
 		o.key = key
+		o.secretSet = true
 	}
 }
 
@@ -117,7 +139,16 @@ func WithCustomKeyFunc(keyFunc jwtlib.Keyfunc) Option {
 	}
 }
 
+// WithSessionValidator rejects tokens whose sid claim is not an active session.
+// Tokens without a sid claim are not checked.
+func WithSessionValidator(validator SessionValidator) Option {
+	return func(o *options) {
+		o.sessionCheck = validator
+	}
+}
+
 // Server is a server auth middleware. Check the token and extract the info from token.
+// When a key option fails, every request fails with ErrKeyConfig.
 func Server(opts ...Option) middleware.Middleware {
 	secretKey := os.Getenv("ENV_JWT_SECRET")
 	o := &options{
@@ -128,14 +159,8 @@ func Server(opts ...Option) middleware.Middleware {
 	for _, opt := range opts {
 		opt(o)
 	}
-
-	if o.keyFunc == nil {
-		o.keyFunc = func(t *jwtlib.Token) (interface{}, error) {
-			if t.Header["alg"] == o.signingMethod.Alg() {
-				return []byte(o.key), nil
-			}
-			return nil, ErrMissingKeyFunc
-		}
+	if err := o.useKeys(false); err != nil {
+		return keyConfigError(err)
 	}
 
 	return func(handler middleware.Handler) middleware.Handler {
@@ -147,7 +172,7 @@ func Server(opts ...Option) middleware.Middleware {
 				)
 				if o.autoParse {
 					tokenAuth := header.RequestHeader().Get(string(authorizationKey))
-					tokenInfo, authErr = parseToken(tokenAuth, o)
+					tokenInfo, authErr = parseToken(ctx, tokenAuth, o)
 					if tokenInfo != nil {
 						ctx = NewContext(ctx, tokenInfo.Claims)
 						ctx = context.WithValue(ctx, authorizationKey, tokenInfo.Raw)
@@ -172,7 +197,7 @@ func Server(opts ...Option) middleware.Middleware {
 
 				if tokenInfo == nil {
 					tokenAuth := header.RequestHeader().Get(string(authorizationKey))
-					tokenInfo, err := parseToken(tokenAuth, o)
+					tokenInfo, err := parseToken(ctx, tokenAuth, o)
 					if err != nil {
 						return nil, err
 					}
@@ -187,7 +212,7 @@ func Server(opts ...Option) middleware.Middleware {
 	}
 }
 
-func parseToken(token string, o *options) (*jwtlib.Token, error) {
+func parseToken(ctx context.Context, token string, o *options) (*jwtlib.Token, error) {
 	auths := strings.SplitN(token, " ", 2)
 	if len(auths) != 2 || !strings.EqualFold(auths[0], string(bearerWord)) {
 		return nil, ErrMissingJwtToken
@@ -208,13 +233,36 @@ func parseToken(token string, o *options) (*jwtlib.Token, error) {
 	if !tokenInfo.Valid {
 		return nil, ErrTokenInvalid
 	}
-	if tokenInfo.Method != o.signingMethod {
+	if !o.validMethod(tokenInfo.Method) {
 		return nil, ErrUnSupportSigningMethod
 	}
+	if o.sessionCheck != nil {
+		if sid := sessionId(tokenInfo.Claims); len(sid) > 0 {
+			active, err := o.sessionCheck(ctx, sid)
+			if err != nil {
+				return nil, ErrSessionUnavailable.WithCause(err)
+			}
+			if !active {
+				return nil, ErrSessionRevoked
+			}
+		}
+	}
 	return tokenInfo, err
 }
 
-// Client is a client jwt middleware.
+func sessionId(claims jwtlib.Claims) string {
+	switch c := claims.(type) {
+	case jwtlib.MapClaims:
+		sid, _ := c[sessionIdClaim].(string)
+		return sid
+	case interface{ GetSessionId() string }:
+		return c.GetSessionId()
+	}
+	return ""
+}
+
+// Client is a client jwt middleware. When a key option fails, every request fails
+// with ErrKeyConfig.
 func Client(opts ...Option) middleware.Middleware {
 	claims := jwtlib.RegisteredClaims{}
 	secretKey := os.Getenv("ENV_JWT_SECRET")
@@ -227,14 +275,8 @@ func Client(opts ...Option) middleware.Middleware {
 	for _, opt := range opts {
 		opt(o)
 	}
-
-	if o.keyFunc == nil {
-		o.keyFunc = func(t *jwtlib.Token) (interface{}, error) {
-			if t.Header["alg"] == o.signingMethod.Alg() {
-				return []byte(o.key), nil
-			}
-			return nil, ErrMissingKeyFunc
-		}
+	if err := o.useKeys(true); err != nil {
+		return keyConfigError(err)
 	}
 
 	return func(handler middleware.Handler) middleware.Handler {
@@ -270,6 +312,16 @@ func Client(opts ...Option) middleware.Middleware {
 	}
 }
 
+// keyConfigError fails every request, the cause is logged once.
+func keyConfigError(err error) middleware.Middleware {
+	log.Errorf("jwt: %v", err)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, ErrKeyConfig.WithCause(err)
+		}
+	}
+}
+
 // NewContext put auth info into context
 func NewContext(ctx context.Context, info jwtlib.Claims) context.Context {
 	return context.WithValue(ctx, authKey{}, info)
@@ -296,6 +348,17 @@ func GetUserId(ctx context.Context) (string, error) {
 	return userId, nil
 }
 
+// GetSessionId returns the sid claim of the authenticated token.
+func GetSessionId(ctx context.Context) string {
+	value, ok := FromContext(ctx)
+
+	if !ok {
+		return ""
+	}
+
+	return sessionId(value)
+}
+
 func GetEmail(ctx context.Context) string {
 	value, ok := FromContext(ctx)
 
@@ -325,14 +388,8 @@ func ClientGrpcAuth(ctx context.Context, opts ...Option) (context.Context, error
 	for _, opt := range opts {
 		opt(o)
 	}
-
-	if o.keyFunc == nil {
-		o.keyFunc = func(t *jwtlib.Token) (interface{}, error) {
-			if t.Header["alg"] == o.signingMethod.Alg() {
-				return []byte(o.key), nil
-			}
-			return nil, ErrMissingKeyFunc
-		}
+	if err := o.useKeys(true); err != nil {
+		return nil, err
 	}
 
 	if tokenStr, ok := ctx.Value(authorizationKey).(string); ok {
@@ -358,7 +415,9 @@ func ClientGrpcAuth(ctx context.Context, opts ...Option) (context.Context, error
 	return metadata.NewOutgoingContext(ctx, meta), nil
 }
 
-func GeneratorJwtToken(ctx context.Context, userId string) (context.Context, error) {
+// GeneratorJwtToken signs an access token of userId valid for 24 hours, with the
+// secret or the private key of the options, and puts it into ctx as "accessToken".
+func GeneratorJwtToken(ctx context.Context, userId string, opts ...Option) (context.Context, error) {
 	claims := jwtlib.RegisteredClaims{
 		Subject: userId,
 		ExpiresAt: jwtlib.NewNumericDate(
@@ -373,12 +432,14 @@ func GeneratorJwtToken(ctx context.Context, userId string) (context.Context, err
 		claims:        func() jwtlib.Claims { return claims },
 		tokenHeader: map[string]interface{}{
 			"typ": "JWT",
-			"alg": "HS256",
-		},
-		keyFunc: func(token *jwtlib.Token) (interface{}, error) {
-			return []byte(secretKey), nil
 		},
 	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.useKeys(true); err != nil {
+		return ctx, err
+	}
 
 	token := jwtlib.NewWithClaims(o.signingMethod, o.claims())
 	if o.tokenHeader != nil {
@@ -2,14 +2,25 @@ package jwt
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	stderrors "errors"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	jwtlib "github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
 
+	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/transport"
 )
 
@@ -173,6 +184,172 @@ func TestSever_WithAuthTokenInActive(t *testing.T) {
 	require.Equal(t, err, ErrTokenInvalid)
 }
 
+func getSessionToken(key string, sid string) string {
+	claim := jwtlib.MapClaims{
+		"exp": time.Now().AddDate(0, 0, 1).Unix(),
+		"sub": "123456789",
+		"sid": sid,
+	}
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, claim)
+	ss, err := token.SignedString([]byte(key))
+	if err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+func TestSever_WithSessionValidator(t *testing.T) {
+	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return GetSessionId(ctx), nil
+	}
+	validator := func(ctx context.Context, sid string) (bool, error) {
+		return sid == "active", nil
+	}
+	server := Server(WithRequired(true), WithSecretKey("session-secret"), WithSessionValidator(validator))
+
+	hc := headerCarrier{}
+	hc.Set("Authorization", "Bearer "+getSessionToken("session-secret", "active"))
+	ctx := transport.NewServerContext(context.Background(), &Transport{reqHeader: hc})
+	sid, err := server(hs)(ctx, "foo")
+	require.Nil(t, err)
+	require.Equal(t, "active", sid)
+
+	hc.Set("Authorization", "Bearer "+getSessionToken("session-secret", "revoked"))
+	_, err = server(hs)(ctx, "foo")
+	require.Equal(t, err, ErrSessionRevoked)
+}
+
+func TestSever_WithSessionValidatorError(t *testing.T) {
+	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	validator := func(ctx context.Context, sid string) (bool, error) {
+		return false, stderrors.New("connection refused")
+	}
+	server := Server(WithRequired(true), WithSecretKey("session-secret"), WithSessionValidator(validator))
+
+	hc := headerCarrier{}
+	hc.Set("Authorization", "Bearer "+getSessionToken("session-secret", "active"))
+	ctx := transport.NewServerContext(context.Background(), &Transport{reqHeader: hc})
+	_, err := server(hs)(ctx, "foo")
+	require.True(t, errors.Is(err, ErrSessionUnavailable))
+	require.Equal(t, 503, int(errors.FromError(err).Code))
+}
+
+func pemKeys(t *testing.T, private crypto.Signer) (privatePEM, publicPEM []byte) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.Nil(t, err)
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	der, err = x509.MarshalPKIXPublicKey(private.Public())
+	require.Nil(t, err)
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return privatePEM, publicPEM
+}
+
+func TestKeys_SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return GetUserId(ctx)
+	}
+
+	for alg, key := range map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey} {
+		t.Run(alg, func(t *testing.T) {
+			privatePEM, publicPEM := pemKeys(t, key)
+			path := filepath.Join(t.TempDir(), "private.pem")
+			require.Nil(t, os.WriteFile(path, privatePEM, 0o600))
+			t.Setenv("JWT_PUBLIC_KEY", string(publicPEM))
+			server := Server(WithRequired(true), WithPublicKeyEnv("JWT_PUBLIC_KEY"))
+
+			ctx, err := GeneratorJwtToken(context.Background(), "42", WithPrivateKeyFile(path))
+			require.Nil(t, err)
+			token := ctx.Value("accessToken").(string)
+			hc := headerCarrier{}
+			hc.Set("Authorization", "Bearer "+token)
+			userId, err := server(hs)(transport.NewServerContext(ctx, &Transport{reqHeader: hc}), "foo")
+			require.Nil(t, err)
+			require.Equal(t, "42", userId)
+
+			out := headerCarrier{}
+			client := Client(WithPrivateKeyPEM(privatePEM), WithClaims(func() jwtlib.Claims {
+				return jwtlib.RegisteredClaims{Subject: "7"}
+			}))
+			_, err = client(func(ctx context.Context, in interface{}) (interface{}, error) {
+				return nil, nil
+			})(transport.NewClientContext(context.Background(), &Transport{reqHeader: out}), "foo")
+			require.Nil(t, err)
+			parsed, _, err := jwtlib.NewParser().ParseUnverified(out.Get("Authorization")[7:], jwtlib.MapClaims{})
+			require.Nil(t, err)
+			require.Equal(t, alg, parsed.Method.Alg())
+			userId, err = server(hs)(transport.NewServerContext(context.Background(), &Transport{reqHeader: out}), "foo")
+			require.Nil(t, err)
+			require.Equal(t, "7", userId)
+		})
+	}
+}
+
+func TestKeys_RejectOtherKeys(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	_, publicPEM := pemKeys(t, key)
+	otherPEM, _ := pemKeys(t, other)
+	server := Server(WithRequired(true), WithSecretKey(""), WithPublicKeyPEM(publicPEM))
+	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	ctx, err := GeneratorJwtToken(context.Background(), "42", WithPrivateKeyPEM(otherPEM))
+	require.Nil(t, err)
+	hc := headerCarrier{}
+	hc.Set("Authorization", "Bearer "+ctx.Value("accessToken").(string))
+	_, err = server(hs)(transport.NewServerContext(ctx, &Transport{reqHeader: hc}), "foo")
+	require.Equal(t, ErrTokenInvalid, err)
+
+	// without a secret, HS256 tokens signed with an empty key are rejected
+	hc.Set("Authorization", "Bearer "+getSessionToken("", "sid"))
+	_, err = server(hs)(transport.NewServerContext(ctx, &Transport{reqHeader: hc}), "foo")
+	require.Equal(t, ErrTokenInvalid, err)
+
+	_, err = GeneratorJwtToken(context.Background(), "42", WithPrivateKeyPEM([]byte("not a key")))
+	require.NotNil(t, err)
+
+	// a missing key fails the requests instead of the startup
+	server = Server(WithRequired(true), WithPublicKeyFile(filepath.Join(t.TempDir(), "missing.pem")))
+	_, err = server(hs)(transport.NewServerContext(ctx, &Transport{reqHeader: hc}), "foo")
+	require.True(t, errors.Is(err, ErrKeyConfig))
+	client := Client(WithPrivateKeyPEM([]byte("not a key")))
+	_, err = client(hs)(transport.NewClientContext(context.Background(), &Transport{reqHeader: headerCarrier{}}), "foo")
+	require.True(t, errors.Is(err, ErrKeyConfig))
+}
+
+func TestKeys_SecretNeedsOptIn(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	_, publicPEM := pemKeys(t, key)
+	t.Setenv("ENV_JWT_SECRET", "env-secret")
+	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	hc := headerCarrier{}
+	hc.Set("Authorization", "Bearer "+getSessionToken("env-secret", "sid"))
+	ctx := transport.NewServerContext(context.Background(), &Transport{reqHeader: hc})
+
+	// the ENV_JWT_SECRET default does not verify tokens once public keys are set
+	server := Server(WithRequired(true), WithPublicKeyPEM(publicPEM))
+	_, err = server(hs)(ctx, "foo")
+	require.Equal(t, ErrTokenInvalid, err)
+
+	server = Server(WithRequired(true), WithPublicKeyPEM(publicPEM), WithSecretKey("env-secret"))
+	_, err = server(hs)(ctx, "foo")
+	require.Nil(t, err)
+}
+
 // func TestSever_WithAuthXUserSuccess(t *testing.T) {
 // 	hs := func(ctx context.Context, in interface{}) (interface{}, error) {
 // 		return nil, nil
@@ -0,0 +1,278 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/config"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// WithPublicKeyPEM verifies tokens with a PEM encoded RSA, ECDSA or Ed25519 public key
+// or certificate. The key verifies the algs of its type: RS*/PS* for RSA, ES256, ES384
+// or ES512 by curve for ECDSA and EdDSA for Ed25519.
+func WithPublicKeyPEM(data []byte) Option {
+	key, err := parsePublicKey(data)
+	return func(o *options) {
+		o.addPublicKey(key, err)
+	}
+}
+
+// WithPublicKeyFile loads the public key from a PEM file, see WithPublicKeyPEM.
+func WithPublicKeyFile(path string) Option {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keyError(err)
+	}
+	return WithPublicKeyPEM(data)
+}
+
+// WithPublicKeyEnv loads the public key from the PEM in an environment variable, see WithPublicKeyPEM.
+func WithPublicKeyEnv(name string) Option {
+	data, err := pemFromEnv(name)
+	if err != nil {
+		return keyError(err)
+	}
+	return WithPublicKeyPEM(data)
+}
+
+// WithPrivateKeyPEM signs tokens with a PEM encoded RSA, ECDSA or Ed25519 private key
+// (PKCS#8, PKCS#1 or SEC 1). The signing method defaults to RS256, ES256/384/512 by
+// curve or EdDSA, the public key of the private key also verifies tokens.
+func WithPrivateKeyPEM(data []byte) Option {
+	key, err := parsePrivateKey(data)
+	return func(o *options) {
+		if err != nil {
+			o.setKeyErr(err)
+			return
+		}
+		o.privateKey = key
+		o.addPublicKey(key.Public(), nil)
+	}
+}
+
+// WithPrivateKeyFile loads the private key from a PEM file, see WithPrivateKeyPEM.
+func WithPrivateKeyFile(path string) Option {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keyError(err)
+	}
+	return WithPrivateKeyPEM(data)
+}
+
+// WithPrivateKeyEnv loads the private key from the PEM in an environment variable, see WithPrivateKeyPEM.
+func WithPrivateKeyEnv(name string) Option {
+	data, err := pemFromEnv(name)
+	if err != nil {
+		return keyError(err)
+	}
+	return WithPrivateKeyPEM(data)
+}
+
+// WithKeySource loads PEM keys from a kratos config source, such as vault.NewSource(),
+// publicKey and privateKey are the names of the values, an empty name is skipped.
+// The source is loaded when the option is created.
+func WithKeySource(src config.Source, publicKey, privateKey string) Option {
+	kvs, err := src.Load()
+	if err != nil {
+		return keyError(err)
+	}
+	values := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		values[kv.Key] = kv.Value
+	}
+	var opts []Option
+	for _, k := range []struct {
+		name string
+		opt  func([]byte) Option
+	}{{publicKey, WithPublicKeyPEM}, {privateKey, WithPrivateKeyPEM}} {
+		if len(k.name) == 0 {
+			continue
+		}
+		value, ok := values[k.name]
+		if !ok {
+			return keyError(fmt.Errorf("jwt: key %s not found in config source", k.name))
+		}
+		opts = append(opts, k.opt(unescapePEM(value)))
+	}
+	return func(o *options) {
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+func keyError(err error) Option {
+	return func(o *options) {
+		o.setKeyErr(err)
+	}
+}
+
+func (o *options) setKeyErr(err error) {
+	if o.keyErr == nil {
+		o.keyErr = err
+	}
+}
+
+func (o *options) addPublicKey(key crypto.PublicKey, err error) {
+	if err == nil {
+		var algs []string
+		algs, err = keyAlgs(key)
+		for _, alg := range algs {
+			if o.publicKeys == nil {
+				o.publicKeys = map[string]crypto.PublicKey{}
+			}
+			o.publicKeys[alg] = key
+		}
+	}
+	if err != nil {
+		o.setKeyErr(err)
+	}
+}
+
+// useKeys selects the signing method of the private key, the accepted algs and the
+// default key func, it returns the error of a key option. Once public keys are set,
+// only their algs are accepted unless the secret was set with WithSecretKey, the
+// ENV_JWT_SECRET default is dropped.
+func (o *options) useKeys(sign bool) error {
+	if o.keyErr != nil {
+		return o.keyErr
+	}
+	if o.privateKey != nil {
+		if _, ok := o.signingMethod.(*jwtlib.SigningMethodHMAC); ok {
+			algs, _ := keyAlgs(o.privateKey.Public())
+			o.signingMethod = jwtlib.GetSigningMethod(algs[0])
+		}
+	}
+	if !sign && len(o.publicKeys) > 0 && !o.secretSet {
+		o.key = ""
+	}
+	o.algs = map[string]bool{}
+	for alg := range o.publicKeys {
+		o.algs[alg] = true
+	}
+	if len(o.publicKeys) == 0 || len(o.key) > 0 {
+		o.algs[o.signingMethod.Alg()] = true
+	}
+	if o.keyFunc == nil {
+		if sign {
+			o.keyFunc = o.signingKey
+		} else {
+			o.keyFunc = o.verificationKey
+		}
+	}
+	return nil
+}
+
+// signingKey returns the private key, or the secret for HMAC signing methods.
+func (o *options) signingKey(t *jwtlib.Token) (interface{}, error) {
+	if t.Header["alg"] != o.signingMethod.Alg() {
+		return nil, ErrMissingKeyFunc
+	}
+	if _, ok := o.signingMethod.(*jwtlib.SigningMethodHMAC); !ok && o.privateKey != nil {
+		return o.privateKey, nil
+	}
+	return []byte(o.key), nil
+}
+
+// verificationKey picks the key by the alg of the token, the secret verifies the
+// signing method when it is accepted.
+func (o *options) verificationKey(t *jwtlib.Token) (interface{}, error) {
+	alg, _ := t.Header["alg"].(string)
+	if key, ok := o.publicKeys[alg]; ok {
+		return key, nil
+	}
+	if alg == o.signingMethod.Alg() && o.algs[alg] {
+		return []byte(o.key), nil
+	}
+	return nil, ErrMissingKeyFunc
+}
+
+// validMethod reports whether tokens signed with method are accepted.
+func (o *options) validMethod(method jwtlib.SigningMethod) bool {
+	return o.algs[method.Alg()]
+}
+
+func keyAlgs(key crypto.PublicKey) ([]string, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return []string{"ES256"}, nil
+		case elliptic.P384():
+			return []string{"ES384"}, nil
+		case elliptic.P521():
+			return []string{"ES512"}, nil
+		}
+		return nil, errors.New("jwt: unsupported ECDSA curve")
+	case ed25519.PublicKey:
+		return []string{"EdDSA"}, nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported key type %T", key)
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: public key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.New("jwt: unsupported public key format")
+	}
+	return cert.PublicKey, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("jwt: unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("jwt: unsupported private key format")
+}
+
+func pemFromEnv(name string) ([]byte, error) {
+	value := os.Getenv(name)
+	if len(value) == 0 {
+		return nil, fmt.Errorf("jwt: environment variable %s is empty", name)
+	}
+	return unescapePEM([]byte(value)), nil
+}
+
+// unescapePEM restores the newlines of a PEM stored on a single line with \n.
+func unescapePEM(data []byte) []byte {
+	s := string(data)
+	if !strings.Contains(s, "\n") {
+		s = strings.ReplaceAll(s, `\n`, "\n")
+	}
+	return []byte(s)
+}
@@ -0,0 +1,370 @@
+// Package signature verifies signed requests of partners and webhook senders. The
+// signature covers a canonical request string (see CanonicalString) and is made
+// with HMAC-SHA256 or RSA-SHA256 with the key of the sender, selected by its key id.
+// WithWebhookFormat verifies the requests of a webhook.Dispatcher instead.
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/webhook"
+)
+
+type keyIDKey struct{}
+
+const (
+	// Default headers of a signed request.
+	KeyIDHeader     = "X-Key-Id"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	SignatureHeader = "X-Signature"
+
+	// reason holds the error reason.
+	reason = "INVALID_SIGNATURE"
+
+	defaultTolerance = 5 * time.Minute
+	nonceKeyPrefix   = "signature:nonce:"
+)
+
+var (
+	ErrMissingSignature = errors.Unauthorized(reason, "Request signature is missing")
+	ErrUnknownKey       = errors.Unauthorized(reason, "Signing key is unknown")
+	ErrInvalidSignature = errors.Unauthorized(reason, "Request signature is invalid")
+	ErrStaleRequest     = errors.Unauthorized(reason, "Request timestamp is outside the tolerance")
+	ErrReplayedRequest  = errors.Unauthorized(reason, "Request nonce was already used")
+	ErrWrongContext     = errors.Unauthorized(reason, "Wrong context for middleware")
+)
+
+// Algorithm is a signature algorithm.
+type Algorithm string
+
+const (
+	HMACSHA256 Algorithm = "hmac-sha256"
+	RSASHA256  Algorithm = "rsa-sha256"
+)
+
+// Key is the key of a sender. Secret is the HMAC secret, PublicKey verifies RSA
+// signatures and PrivateKey signs them in SignRequest.
+type Key struct {
+	Algorithm  Algorithm
+	Secret     []byte
+	PublicKey  *rsa.PublicKey
+	PrivateKey *rsa.PrivateKey
+}
+
+// KeyResolver returns the key of a key id, a nil key rejects the request.
+type KeyResolver func(ctx context.Context, keyID string) (*Key, error)
+
+// StaticKeys resolves key ids from a map.
+func StaticKeys(keys map[string]*Key) KeyResolver {
+	return func(ctx context.Context, keyID string) (*Key, error) {
+		return keys[keyID], nil
+	}
+}
+
+// Option is signature option.
+type Option func(*options)
+
+type options struct {
+	keyIDHeader     string
+	timestampHeader string
+	nonceHeader     string
+	signatureHeader string
+	tolerance       time.Duration
+	nonces          redisdb.Store
+	webhookKeyID    string
+}
+
+// WithHeaders changes the headers holding the key id, the timestamp, the nonce and the signature.
+func WithHeaders(keyID, timestamp, nonce, signature string) Option {
+	return func(o *options) {
+		o.keyIDHeader = keyID
+		o.timestampHeader = timestamp
+		o.nonceHeader = nonce
+		o.signatureHeader = signature
+	}
+}
+
+// WithTolerance sets how far the request timestamp may be from now, default 5 minutes.
+func WithTolerance(tolerance time.Duration) Option {
+	return func(o *options) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithNonceStore rejects replays: a nonce is required and accepted once per key id
+// within twice the tolerance. Works with *redisdb.RedisClient or memstore.Store.
+func WithNonceStore(store redisdb.Store) Option {
+	return func(o *options) {
+		o.nonces = store
+	}
+}
+
+// WithWebhookFormat verifies requests sent by a webhook.Dispatcher with the HMAC key
+// keyID: the webhook.SignatureHeader t=<unix>,v1=<hex> signs <id>.<unix>.<body> and
+// the webhook.IDHeader with the signed timestamp is the nonce, so retries of a delivery
+// pass and a replayed request does not. The headers of WithHeaders are not used.
+func WithWebhookFormat(keyID string) Option {
+	return func(o *options) {
+		o.webhookKeyID = keyID
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		keyIDHeader:     KeyIDHeader,
+		timestampHeader: TimestampHeader,
+		nonceHeader:     NonceHeader,
+		signatureHeader: SignatureHeader,
+		tolerance:       defaultTolerance,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Server is a kratos http server middleware verifying request signatures. The key id
+// of a verified request is available with FromContext.
+func Server(resolver KeyResolver, opts ...Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			r, ok := khttp.RequestFromServerContext(ctx)
+			if !ok {
+				return nil, ErrWrongContext
+			}
+			verifier := verify
+			if len(o.webhookKeyID) > 0 {
+				verifier = verifyWebhook
+			}
+			keyID, err := verifier(ctx, r, resolver, o)
+			if err != nil {
+				return nil, err
+			}
+			return handler(NewContext(ctx, keyID), req)
+		}
+	}
+}
+
+func verify(ctx context.Context, r *http.Request, resolver KeyResolver, o *options) (string, error) {
+	keyID := r.Header.Get(o.keyIDHeader)
+	ts := r.Header.Get(o.timestampHeader)
+	nonce := r.Header.Get(o.nonceHeader)
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get(o.signatureHeader))
+	if len(keyID) == 0 || len(ts) == 0 || len(sig) == 0 || err != nil {
+		return "", ErrMissingSignature
+	}
+	if o.nonces != nil && len(nonce) == 0 {
+		return "", ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", ErrMissingSignature
+	}
+	if d := time.Since(time.Unix(unix, 0)); d > o.tolerance || d < -o.tolerance {
+		return "", ErrStaleRequest
+	}
+	key, err := resolve(ctx, resolver, keyID)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+	if !key.verify(CanonicalString(r, ts, nonce, body), sig) {
+		return "", ErrInvalidSignature
+	}
+	if err := useNonce(ctx, o, keyID, nonce, ts); err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
+
+func verifyWebhook(ctx context.Context, r *http.Request, resolver KeyResolver, o *options) (string, error) {
+	keyID := o.webhookKeyID
+	id := r.Header.Get(webhook.IDHeader)
+	header := r.Header.Get(webhook.SignatureHeader)
+	if len(id) == 0 || len(header) == 0 {
+		return "", ErrMissingSignature
+	}
+	key, err := resolve(ctx, resolver, keyID)
+	if err != nil {
+		return "", err
+	}
+	if key.Algorithm != HMACSHA256 || len(key.Secret) == 0 {
+		return "", ErrInvalidSignature
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+	err = webhook.Verify([]string{string(key.Secret)}, id, header, body, o.tolerance)
+	if errors.Is(err, webhook.ErrStaleTimestamp) {
+		return "", ErrStaleRequest
+	}
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	// retries keep the delivery id with a new signed timestamp, both make the nonce
+	signedAt, err := webhook.Timestamp(header)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	ts := strconv.FormatInt(signedAt.Unix(), 10)
+	if err := useNonce(ctx, o, keyID, id+"."+ts, ts); err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
+
+// resolve returns the key of keyID, a failed lookup is an internal error.
+func resolve(ctx context.Context, resolver KeyResolver, keyID string) (*Key, error) {
+	key, err := resolver(ctx, keyID)
+	if err != nil {
+		return nil, errors.InternalServer(reason, "Signing key lookup failed").WithCause(err)
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// useNonce records the nonce of an authentic request, so forged ones cannot burn it.
+func useNonce(ctx context.Context, o *options, keyID, nonce, ts string) error {
+	if o.nonces == nil {
+		return nil
+	}
+	fresh, err := o.nonces.SetNX(ctx, nonceKeyPrefix+keyID+":"+nonce, ts, 2*o.tolerance)
+	if err != nil {
+		return errors.InternalServer(reason, "Nonce check failed").WithCause(err)
+	}
+	if !fresh {
+		return ErrReplayedRequest
+	}
+	return nil
+}
+
+// readBody reads the request body and puts it back for the handler.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// CanonicalString is the signed content of a request, its lines are the method, the
+// escaped path, the query sorted by key and value, the timestamp, the nonce and the
+// hex sha256 of the body.
+func CanonicalString(r *http.Request, timestamp, nonce string, body []byte) string {
+	query := r.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		query.Encode(), // sorted by key
+		timestamp,
+		nonce,
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+func (k *Key) verify(content string, sig []byte) bool {
+	switch k.Algorithm {
+	case HMACSHA256:
+		return len(k.Secret) > 0 && hmac.Equal(hmacSHA256(k.Secret, content), sig)
+	case RSASHA256:
+		if k.PublicKey == nil {
+			return false
+		}
+		sum := sha256.Sum256([]byte(content))
+		return rsa.VerifyPKCS1v15(k.PublicKey, crypto.SHA256, sum[:], sig) == nil
+	}
+	return false
+}
+
+func (k *Key) sign(content string) ([]byte, error) {
+	switch k.Algorithm {
+	case HMACSHA256:
+		return hmacSHA256(k.Secret, content), nil
+	case RSASHA256:
+		if k.PrivateKey == nil {
+			return nil, errors.New(500, reason, "RSA private key is missing")
+		}
+		sum := sha256.Sum256([]byte(content))
+		return rsa.SignPKCS1v15(rand.Reader, k.PrivateKey, crypto.SHA256, sum[:])
+	}
+	return nil, errors.New(500, reason, "Unsupported signature algorithm "+string(k.Algorithm))
+}
+
+func hmacSHA256(secret []byte, content string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(content))
+	return h.Sum(nil)
+}
+
+// SignRequest signs r, for partners calling a service and for tests. The headers are
+// the defaults unless changed by WithHeaders, the other options are ignored.
+func SignRequest(r *http.Request, keyID string, key *Key, opts ...Option) error {
+	o := newOptions(opts)
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	n := hex.EncodeToString(nonce)
+	sig, err := key.sign(CanonicalString(r, ts, n, body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set(o.keyIDHeader, keyID)
+	r.Header.Set(o.timestampHeader, ts)
+	r.Header.Set(o.nonceHeader, n)
+	r.Header.Set(o.signatureHeader, base64.StdEncoding.EncodeToString(sig))
+	return nil
+}
+
+// NewContext puts the key id of a verified request into ctx.
+func NewContext(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, keyIDKey{}, keyID)
+}
+
+// FromContext returns the key id of the verified request.
+func FromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(keyIDKey{}).(string)
+	return keyID, ok
+}
@@ -0,0 +1,157 @@
+package signature
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+
+	"github.com/nartvt/go-core/database/redisdb/memstore"
+	"github.com/nartvt/go-core/webhook"
+)
+
+func newServer(t *testing.T, resolver KeyResolver, opts ...Option) *httptest.Server {
+	srv := khttp.NewServer(khttp.Middleware(Server(resolver, opts...)))
+	srv.Route("/").POST("/callback", func(c khttp.Context) error {
+		var in map[string]interface{}
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		h := c.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			keyID, _ := FromContext(ctx)
+			return map[string]string{"partner": keyID}, nil
+		})
+		out, err := h(c, &in)
+		if err != nil {
+			return err
+		}
+		return c.Result(http.StatusOK, out)
+	})
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRequest(t *testing.T, url string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, url+"/callback?b=2&a=1", strings.NewReader(`{"order":1}`))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func do(t *testing.T, req *http.Request) int {
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServer_HMAC(t *testing.T) {
+	key := &Key{Algorithm: HMACSHA256, Secret: []byte("secret")}
+	server := newServer(t, StaticKeys(map[string]*Key{"partner": key}), WithNonceStore(memstore.New()))
+
+	req := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", key))
+	replay := req.Clone(context.Background())
+	require.Equal(t, http.StatusOK, do(t, req))
+
+	replay.Body = newRequest(t, server.URL).Body
+	require.Equal(t, http.StatusUnauthorized, do(t, replay))
+
+	tampered := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(tampered, "partner", key))
+	tampered.URL.RawQuery = "a=1&b=3"
+	require.Equal(t, http.StatusUnauthorized, do(t, tampered))
+
+	unknown := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(unknown, "other", key))
+	require.Equal(t, http.StatusUnauthorized, do(t, unknown))
+
+	require.Equal(t, http.StatusUnauthorized, do(t, newRequest(t, server.URL)))
+}
+
+func TestServer_RSA(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	server := newServer(t, StaticKeys(map[string]*Key{
+		"partner": {Algorithm: RSASHA256, PublicKey: &private.PublicKey},
+	}))
+
+	req := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", &Key{Algorithm: RSASHA256, PrivateKey: private}))
+	require.Equal(t, http.StatusOK, do(t, req))
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	req = newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", &Key{Algorithm: RSASHA256, PrivateKey: other}))
+	require.Equal(t, http.StatusUnauthorized, do(t, req))
+}
+
+func TestServer_StaleTimestamp(t *testing.T) {
+	key := &Key{Algorithm: HMACSHA256, Secret: []byte("secret")}
+	server := newServer(t, StaticKeys(map[string]*Key{"partner": key}))
+
+	req := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", key))
+	req.Header.Set(TimestampHeader, "1000")
+	require.Equal(t, http.StatusUnauthorized, do(t, req))
+}
+
+func TestServer_Headers(t *testing.T) {
+	key := &Key{Algorithm: HMACSHA256, Secret: []byte("secret")}
+	headers := WithHeaders("X-Partner", "X-Partner-Time", "X-Partner-Nonce", "X-Partner-Signature")
+	server := newServer(t, StaticKeys(map[string]*Key{"partner": key}), headers)
+
+	req := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", key, headers))
+	require.Equal(t, "partner", req.Header.Get("X-Partner"))
+	require.Empty(t, req.Header.Get(SignatureHeader))
+	require.Equal(t, http.StatusOK, do(t, req))
+}
+
+func TestServer_ResolverError(t *testing.T) {
+	server := newServer(t, func(ctx context.Context, keyID string) (*Key, error) {
+		return nil, errors.New("dial tcp 10.0.0.7:6379: connection refused")
+	})
+	req := newRequest(t, server.URL)
+	require.Nil(t, SignRequest(req, "partner", &Key{Algorithm: HMACSHA256, Secret: []byte("secret")}))
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.NotContains(t, string(body), "10.0.0.7")
+}
+
+func TestServer_Webhook(t *testing.T) {
+	key := &Key{Algorithm: HMACSHA256, Secret: []byte("secret")}
+	server := newServer(t, StaticKeys(map[string]*Key{"shop": key}), WithWebhookFormat("shop"), WithNonceStore(memstore.New()))
+
+	// signed like webhook.Dispatcher, during a rotation with the new and the old secret
+	signed := func(id string, secrets []string, at time.Time) *http.Request {
+		req := newRequest(t, server.URL)
+		body := []byte(`{"order":1}`)
+		req.Header.Set(webhook.IDHeader, id)
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secrets, id, at, body))
+		return req
+	}
+	at := time.Now()
+	require.Equal(t, http.StatusOK, do(t, signed("d1", []string{"next", "secret"}, at)))
+	require.Equal(t, http.StatusUnauthorized, do(t, signed("d1", []string{"next", "secret"}, at)), "replayed request")
+	require.Equal(t, http.StatusOK, do(t, signed("d1", []string{"secret"}, at.Add(time.Second))), "retry of the delivery")
+	require.Equal(t, http.StatusUnauthorized, do(t, signed("d2", []string{"other"}, time.Now())))
+	require.Equal(t, http.StatusUnauthorized, do(t, signed("d3", []string{"secret"}, time.Now().Add(-time.Hour))))
+	require.Equal(t, http.StatusUnauthorized, do(t, newRequest(t, server.URL)))
+}
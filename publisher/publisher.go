@@ -1,17 +1,27 @@
+// Package publisher is a NATS publisher bound to a default topic.
+//
+// Deprecated: use pubsub.Publisher implemented by the pubsub/nats package instead.
 package publisher
 
 import (
 	"encoding/json"
 	"errors"
+	"sync"
 
 	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/pubsub"
 )
 
 type Publisher struct {
-	topic   string
-	host    string
+	topic string
+	host  string
+	opts  []nats.Option
+
+	codecs *pubsub.Codecs
+
+	mu      sync.Mutex
 	natsCli *nats.Conn
-	opts    []nats.Option
 }
 
 func NewPublisher(host string, topic string, opts ...nats.Option) (*Publisher, error) {
@@ -28,42 +38,61 @@ func NewPublisher(host string, topic string, opts ...nats.Option) (*Publisher, e
 	}, nil
 }
 
+// WithCodecs encodes published data with the codec codecs selects for the topic
+// and sends its content type header, usually with pubsub.DefaultCodecs. Without
+// it data is json encoded and sent without headers. Proto messages are then
+// encoded with protojson and headers need a NATS server 2.2 or later.
+func (p *Publisher) WithCodecs(codecs *pubsub.Codecs) *Publisher {
+	p.codecs = codecs
+	return p
+}
+
+// Publish encodes data as json, or with the codecs set by WithCodecs.
 func (p *Publisher) Publish(data interface{}) error {
-	b, err := json.Marshal(data)
+	return p.PublishWithTopic(p.topic, data)
+}
+
+func (p *Publisher) PublishWithTopic(topic string, data interface{}) error {
+	m, err := encode(p.codecs, topic, data)
 	if err != nil {
 		return err
 	}
 
-	err = p.natsCli.Publish(p.topic, b)
+	p.mu.Lock()
+	conn := p.natsCli
+	p.mu.Unlock()
+
+	err = conn.PublishMsg(m)
 	if err == nats.ErrConnectionClosed {
-		// retry if conn is close
-		conn, err := nats.Connect(p.host, p.opts...)
-		if err != nil {
-			return errors.New("RESTART_NATS_CONN_FAILED")
+		// retry if conn is close, concurrent publishers share the new connection
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.natsCli == conn {
+			newConn, err := nats.Connect(p.host, p.opts...)
+			if err != nil {
+				return errors.New("RESTART_NATS_CONN_FAILED")
+			}
+			// the replaced connection is closed so it does not leak
+			conn.Close()
+			p.natsCli = newConn
 		}
-		p.natsCli = conn
-		return p.natsCli.Publish(p.topic, b)
+		return p.natsCli.PublishMsg(m)
 	}
 
 	return err
 }
 
-func (p *Publisher) PublishWithTopic(topic string, data interface{}) error {
-	b, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-
-	err = p.natsCli.Publish(topic, b)
-	if err == nats.ErrConnectionClosed {
-		// retry if conn is close
-		conn, err := nats.Connect(p.host, p.opts...)
+func encode(codecs *pubsub.Codecs, topic string, data interface{}) (*nats.Msg, error) {
+	if codecs == nil {
+		b, err := json.Marshal(data)
 		if err != nil {
-			return errors.New("RESTART_NATS_CONN_FAILED")
+			return nil, err
 		}
-		p.natsCli = conn
-		return p.natsCli.Publish(p.topic, b)
+		return &nats.Msg{Subject: topic, Data: b}, nil
 	}
-
-	return err
+	msg, err := codecs.Encode(topic, data)
+	if err != nil {
+		return nil, err
+	}
+	return &nats.Msg{Subject: topic, Header: nats.Header(msg.Header), Data: msg.Data}, nil
 }
@@ -0,0 +1,44 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func TestPublisher_Codecs(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	srv := natstest.RunServer(&opts)
+	defer srv.Shutdown()
+
+	sub, err := nats.Connect(srv.ClientURL())
+	require.Nil(t, err)
+	defer sub.Close()
+	ch := make(chan *nats.Msg, 2)
+	_, err = sub.ChanSubscribe("orders.created", ch)
+	require.Nil(t, err)
+	require.Nil(t, sub.Flush())
+
+	p, err := NewPublisher(srv.ClientURL(), "orders.created")
+	require.Nil(t, err)
+	require.Nil(t, p.Publish(map[string]int{"id": 1}))
+	require.Nil(t, p.WithCodecs(pubsub.NewCodecs("msgpack")).Publish(map[string]int{"id": 1}))
+
+	for _, contentType := range []string{"", "application/msgpack"} {
+		select {
+		case m := <-ch:
+			require.Equal(t, contentType, m.Header.Get(pubsub.ContentTypeHeader))
+			if len(contentType) == 0 {
+				require.JSONEq(t, `{"id":1}`, string(m.Data))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package broker builds the publisher and subscriber of the configured driver,
+// so switching between NATS and redis is a config change.
+package broker
+
+import (
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/wire"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/pubsub/nats"
+	"github.com/nartvt/go-core/pubsub/redis"
+)
+
+// Drivers of conf.PubSub.
+const (
+	DriverNATS         = "nats"
+	DriverRedis        = "redis"
+	DriverRedisStreams = "redis_streams"
+)
+
+// ProviderSet is broker providers.
+var ProviderSet = wire.NewSet(NewPublisher, NewSubscriber)
+
+// NewPublisher returns the publisher of the configured driver, nats when it is empty.
+func NewPublisher(c *conf.PubSub) (pubsub.Publisher, error) {
+	if err := validate(c); err != nil {
+		return nil, err
+	}
+	switch c.Driver {
+	case "", DriverNATS:
+		return nats.NewPublisher(natsConf(c))
+	case DriverRedis:
+		return redis.NewPublisher(c.Redis), nil
+	case DriverRedisStreams:
+		return redis.NewStreamPublisher(c.Redis, c.RedisStreams), nil
+	}
+	return nil, fmt.Errorf("broker: unknown pubsub driver %s", c.Driver)
+}
+
+// NewSubscriber returns the subscriber of the configured driver, nats when it is empty.
+func NewSubscriber(c *conf.PubSub, logger log.Logger) (pubsub.Subscriber, error) {
+	if err := validate(c); err != nil {
+		return nil, err
+	}
+	switch c.Driver {
+	case "", DriverNATS:
+		nc := natsConf(c)
+		var opts []nats.SubscriberOption
+		if nc.Concurrency > 0 {
+			opts = append(opts, nats.WithConcurrency(int(nc.Concurrency)))
+		}
+		if nc.HandlerTimeout != nil {
+			opts = append(opts, nats.WithHandlerTimeout(nc.HandlerTimeout.AsDuration()))
+		}
+		return nats.NewSubscriber(nc, logger, opts...)
+	case DriverRedis:
+		return redis.NewSubscriber(c.Redis, logger), nil
+	case DriverRedisStreams:
+		return redis.NewStreamSubscriber(c.Redis, c.RedisStreams, logger), nil
+	}
+	return nil, fmt.Errorf("broker: unknown pubsub driver %s", c.Driver)
+}
+
+func validate(c *conf.PubSub) error {
+	if (c.Driver == DriverRedis || c.Driver == DriverRedisStreams) && c.Redis == nil {
+		return fmt.Errorf("broker: pubsub driver %s requires redis config", c.Driver)
+	}
+	return nil
+}
+
+func natsConf(c *conf.PubSub) *conf.Nats {
+	if c.Nats == nil {
+		return &conf.Nats{}
+	}
+	return c.Nats
+}
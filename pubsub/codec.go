@@ -0,0 +1,200 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	// register the default codecs
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	"google.golang.org/protobuf/proto"
+
+	_ "github.com/nartvt/go-core/pubsub/msgpack"
+)
+
+const (
+	// ContentTypeHeader carries the content type of the message data.
+	ContentTypeHeader = "content-type"
+	// MessageTypeHeader carries the full name of the proto message encoded in the data.
+	MessageTypeHeader = "message-type"
+)
+
+const defaultCodec = "json"
+
+var contentTypes = struct {
+	sync.RWMutex
+	codecs map[string]string // content type -> codec name
+	types  map[string]string // codec name -> content type
+}{
+	codecs: map[string]string{
+		"application/json":       "json",
+		"application/x-protobuf": "proto",
+		"application/protobuf":   "proto",
+		"application/msgpack":    "msgpack",
+		"application/x-msgpack":  "msgpack",
+	},
+	types: map[string]string{
+		"json":    "application/json",
+		"proto":   "application/x-protobuf",
+		"msgpack": "application/msgpack",
+	},
+}
+
+// RegisterContentType maps contentType to a codec registered with kratos encoding.RegisterCodec.
+// The first content type registered for a codec is the one set on encoded messages.
+func RegisterContentType(contentType, codecName string) {
+	contentTypes.Lock()
+	defer contentTypes.Unlock()
+	contentTypes.codecs[contentType] = codecName
+	if _, ok := contentTypes.types[codecName]; !ok {
+		contentTypes.types[codecName] = contentType
+	}
+}
+
+// ContentType returns the content type of the codec, application/<name> when unknown.
+func ContentType(codecName string) string {
+	contentTypes.RLock()
+	defer contentTypes.RUnlock()
+	if contentType, ok := contentTypes.types[codecName]; ok {
+		return contentType
+	}
+	return "application/" + codecName
+}
+
+// CodecForContentType returns the codec of contentType, parameters are ignored.
+// An empty content type selects json, an unknown one the kratos codec named
+// after its subtype (application/<name>), nil when there is none.
+func CodecForContentType(contentType string) encoding.Codec {
+	if len(contentType) == 0 {
+		return encoding.GetCodec(defaultCodec)
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	contentTypes.RLock()
+	name, ok := contentTypes.codecs[contentType]
+	contentTypes.RUnlock()
+	if !ok {
+		name = contentType[strings.LastIndex(contentType, "/")+1:]
+		name = strings.TrimPrefix(name, "x-")
+	}
+	return encoding.GetCodec(name)
+}
+
+// Codecs selects the codec used to encode values per topic.
+type Codecs struct {
+	mu     sync.RWMutex
+	def    string
+	topics []topicCodec
+}
+
+type topicCodec struct {
+	pattern string
+	codec   string
+}
+
+// DefaultCodecs is used by Send, it encodes every topic with json until configured.
+var DefaultCodecs = NewCodecs(defaultCodec)
+
+// NewCodecs uses defaultCodec for topics without a codec.
+func NewCodecs(defaultCodec string) *Codecs {
+	return &Codecs{def: defaultCodec}
+}
+
+// Topic encodes topics matching pattern with codec, the pattern accepts the NATS
+// wildcards * and >. The first matching pattern wins.
+func (c *Codecs) Topic(pattern, codec string) *Codecs {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.topics = append(c.topics, topicCodec{pattern: pattern, codec: codec})
+	return c
+}
+
+// Codec returns the name of the codec used for topic.
+func (c *Codecs) Codec(topic string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, tc := range c.topics {
+		if MatchSubject(tc.pattern, topic) {
+			return tc.codec
+		}
+	}
+	return c.def
+}
+
+// Encode encodes v with the codec of topic.
+func (c *Codecs) Encode(topic string, v interface{}) (*Message, error) {
+	return Encode(c.Codec(topic), v)
+}
+
+// Encode encodes v with the named kratos codec and sets the content type header.
+func Encode(codecName string, v interface{}) (*Message, error) {
+	codec := encoding.GetCodec(codecName)
+	if codec == nil {
+		return nil, fmt.Errorf("pubsub: codec %s is not registered", codecName)
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	msg := NewMessage(data)
+	msg.Header.Set(ContentTypeHeader, ContentType(codecName))
+	if m, ok := v.(proto.Message); ok {
+		msg.Header.Set(MessageTypeHeader, string(m.ProtoReflect().Descriptor().FullName()))
+	}
+	return msg, nil
+}
+
+// Decode decodes msg into v with the codec of its content type header, json when it is missing.
+func Decode(msg *Message, v interface{}) error {
+	contentType := msg.Header.Get(ContentTypeHeader)
+	codec := CodecForContentType(contentType)
+	if codec == nil {
+		return fmt.Errorf("pubsub: no codec for content type %s", contentType)
+	}
+	return codec.Unmarshal(msg.Data, v)
+}
+
+// Send encodes v with DefaultCodecs and publishes it on topic.
+func Send(ctx context.Context, p Publisher, topic string, v interface{}) error {
+	msg, err := DefaultCodecs.Encode(topic, v)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, topic, msg)
+}
+
+// DecodeValue decodes msg into a new T, pointer types such as proto messages are allocated.
+func DecodeValue[T any](msg *Message) (T, error) {
+	var v T
+	target := interface{}(&v)
+	if rt := reflect.TypeOf(v); rt != nil && rt.Kind() == reflect.Ptr {
+		v = reflect.New(rt.Elem()).Interface().(T)
+		target = v
+	}
+	err := Decode(msg, target)
+	return v, err
+}
+
+// MatchSubject matches a subject against a pattern with the NATS wildcards, * matches
+// one token and a trailing > matches one or more tokens.
+func MatchSubject(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	pt, tt := strings.Split(pattern, "."), strings.Split(topic, ".")
+	for i, p := range pt {
+		if p == ">" {
+			return len(tt) > i
+		}
+		if i >= len(tt) || (p != "*" && p != tt[i]) {
+			return false
+		}
+	}
+	return len(pt) == len(tt)
+}
@@ -0,0 +1,52 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+)
+
+type order struct {
+	Id    string `json:"id" msgpack:"id"`
+	Total int64  `json:"total" msgpack:"total"`
+}
+
+func TestCodecs_Topic(t *testing.T) {
+	codecs := NewCodecs("json").
+		Topic("orders.*.created", "proto").
+		Topic("metrics.>", "msgpack")
+
+	require.Equal(t, "proto", codecs.Codec("orders.eu.created"))
+	require.Equal(t, "json", codecs.Codec("orders.eu.updated"))
+	require.Equal(t, "msgpack", codecs.Codec("metrics.cpu.load"))
+	require.Equal(t, "json", codecs.Codec("metrics"))
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	for _, name := range []string{"json", "msgpack"} {
+		msg, err := Encode(name, order{Id: "o-1", Total: 42})
+		require.Nil(t, err)
+		require.Equal(t, ContentType(name), msg.Header.Get(ContentTypeHeader))
+
+		v, err := DecodeValue[order](msg)
+		require.Nil(t, err)
+		require.Equal(t, order{Id: "o-1", Total: 42}, v)
+	}
+
+	msg, err := Encode("proto", &conf.Redis{Addr: "127.0.0.1:6379", Db: 2})
+	require.Nil(t, err)
+	require.Equal(t, "application/x-protobuf", msg.Header.Get(ContentTypeHeader))
+
+	v, err := DecodeValue[*conf.Redis](msg)
+	require.Nil(t, err)
+	require.Equal(t, "127.0.0.1:6379", v.Addr)
+	require.Equal(t, int32(2), v.Db)
+}
+
+func TestCodec_DecodeWithoutContentType(t *testing.T) {
+	v, err := DecodeValue[order](NewMessage([]byte(`{"id":"o-1","total":42}`)))
+	require.Nil(t, err)
+	require.Equal(t, order{Id: "o-1", Total: 42}, v)
+}
@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"errors"
+	"net/http"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+
+	"github.com/nartvt/go-core/uerror"
+)
+
+type terminalError struct {
+	err error
+}
+
+func (e *terminalError) Error() string { return e.err.Error() }
+
+func (e *terminalError) Unwrap() error { return e.err }
+
+// Terminal marks err so the message is not redelivered.
+func Terminal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &terminalError{err: err}
+}
+
+// IsTerminal reports whether redelivering the message cannot succeed: errors marked
+// with Terminal and uerror.StatusError or kratos errors with a 4xx code.
+func IsTerminal(err error) bool {
+	if err == nil {
+		return false
+	}
+	var te *terminalError
+	if errors.As(err, &te) {
+		return true
+	}
+	var se *uerror.StatusError
+	if errors.As(err, &se) {
+		return isClientError(int(se.Code))
+	}
+	var ke *kerrors.Error
+	if errors.As(err, &ke) {
+		return isClientError(int(ke.Code))
+	}
+	return false
+}
+
+func isClientError(code int) bool {
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError
+}
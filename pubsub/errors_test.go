@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/uerror"
+)
+
+func TestIsTerminal(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		terminal bool
+	}{
+		{name: "nil", err: nil},
+		{name: "plain", err: errors.New("timeout")},
+		{name: "marked", err: Terminal(errors.New("bad payload")), terminal: true},
+		{name: "wrapped mark", err: fmt.Errorf("handle: %w", Terminal(errors.New("bad payload"))), terminal: true},
+		{name: "kratos 4xx", err: kerrors.NotFound("ORDER_NOT_FOUND", "order not found"), terminal: true},
+		{name: "kratos 5xx", err: kerrors.ServiceUnavailable("UNAVAILABLE", "try later")},
+		{name: "status 4xx", err: &uerror.StatusError{Code: 422, Message: "invalid"}, terminal: true},
+		{name: "status 5xx", err: &uerror.StatusError{Code: 500, Message: "boom"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.terminal, IsTerminal(tc.err))
+		})
+	}
+	require.Nil(t, Terminal(nil))
+}
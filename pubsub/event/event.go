@@ -0,0 +1,320 @@
+// Package event implements a CloudEvents 1.0 envelope for pubsub messages, in
+// binary mode (attributes in ce- headers) and structured JSON mode. Events carry
+// the W3C trace context and the JWT subject of the publisher, so a trace started
+// by an HTTP request continues in the message consumers.
+package event
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nuid"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/nartvt/go-core/middleware/jwt"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// ContentTypeJSON is the data content type of events built by New.
+	ContentTypeJSON = "application/json"
+	// ContentTypeStructured is the content type of structured mode messages.
+	ContentTypeStructured = "application/cloudevents+json"
+
+	headerPrefix      = "ce-"
+	headerContentType = pubsub.ContentTypeHeader
+
+	// attributes of the auth context CloudEvents extension
+	extAuthType = "authtype"
+	extAuthID   = "authid"
+
+	authTypeUser = "app_user"
+)
+
+var (
+	ErrNotEvent       = errors.New("event: message is not a cloudevent")
+	ErrInvalidVersion = errors.New("event: unsupported specversion")
+	// ErrMissingAttribute is returned when a required attribute is missing, the error names it.
+	ErrMissingAttribute = errors.New("event: missing required attribute")
+)
+
+var traceContext = propagation.TraceContext{}
+
+// Event is a CloudEvents 1.0 event, extension attributes are kept in Extensions.
+type Event struct {
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	Data            []byte
+	Extensions      map[string]string
+}
+
+// New json encodes v into an event and injects the trace context and JWT subject of ctx.
+func New(ctx context.Context, source, typ string, v interface{}) (*Event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{
+		ID:              nuid.Next(),
+		Source:          source,
+		Type:            typ,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+		Extensions:      map[string]string{},
+	}
+	e.Inject(ctx)
+	return e, nil
+}
+
+// Inject sets the traceparent, tracestate and auth context extensions from ctx.
+func (e *Event) Inject(ctx context.Context) {
+	if e.Extensions == nil {
+		e.Extensions = map[string]string{}
+	}
+	traceContext.Inject(ctx, propagation.MapCarrier(e.Extensions))
+	if userId, _ := jwt.GetUserId(ctx); len(userId) > 0 {
+		e.Extensions[extAuthType] = authTypeUser
+		e.Extensions[extAuthID] = userId
+	}
+}
+
+// Extract returns ctx with the remote span context of the event as parent and the event itself.
+func (e *Event) Extract(ctx context.Context) context.Context {
+	ctx = traceContext.Extract(ctx, propagation.MapCarrier(e.Extensions))
+	return NewContext(ctx, e)
+}
+
+// AuthID returns the JWT subject of the publisher.
+func (e *Event) AuthID() string {
+	return e.Extensions[extAuthID]
+}
+
+// DataAs decodes the event data into v with the codec of its data content type.
+func (e *Event) DataAs(v interface{}) error {
+	return pubsub.Decode(&pubsub.Message{Header: pubsub.Header{headerContentType: {e.DataContentType}}, Data: e.Data}, v)
+}
+
+// Binary encodes the event in binary mode: attributes are ce- headers and the data is the payload.
+func (e *Event) Binary() *pubsub.Message {
+	msg := pubsub.NewMessage(e.Data)
+	msg.Header.Set(headerPrefix+"specversion", SpecVersion)
+	msg.Header.Set(headerPrefix+"id", e.ID)
+	msg.Header.Set(headerPrefix+"source", e.Source)
+	msg.Header.Set(headerPrefix+"type", e.Type)
+	if len(e.Subject) > 0 {
+		msg.Header.Set(headerPrefix+"subject", e.Subject)
+	}
+	if !e.Time.IsZero() {
+		msg.Header.Set(headerPrefix+"time", e.Time.Format(time.RFC3339Nano))
+	}
+	if len(e.DataContentType) > 0 {
+		msg.Header.Set(headerContentType, e.DataContentType)
+	}
+	for k, v := range e.Extensions {
+		msg.Header.Set(headerPrefix+k, v)
+	}
+	return msg
+}
+
+// Structured encodes the event in structured mode: the payload is the JSON event.
+func (e *Event) Structured() (*pubsub.Message, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	msg := pubsub.NewMessage(data)
+	msg.Header.Set(headerContentType, ContentTypeStructured)
+	return msg, nil
+}
+
+// FromMessage decodes a structured or binary mode message, the id, source, type
+// and specversion attributes are required.
+func FromMessage(msg *pubsub.Message) (*Event, error) {
+	if strings.HasPrefix(msg.Header.Get(headerContentType), ContentTypeStructured) {
+		e := &Event{}
+		if err := json.Unmarshal(msg.Data, e); err != nil {
+			return nil, err
+		}
+		if err := e.validate(); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	version := msg.Header.Get(headerPrefix + "specversion")
+	if len(version) == 0 {
+		return nil, ErrNotEvent
+	}
+	if version != SpecVersion {
+		return nil, ErrInvalidVersion
+	}
+	e := &Event{
+		DataContentType: msg.Header.Get(headerContentType),
+		Data:            msg.Data,
+		Extensions:      map[string]string{},
+	}
+	for k, values := range msg.Header {
+		if len(values) == 0 || !strings.HasPrefix(k, headerPrefix) {
+			continue
+		}
+		v := values[0]
+		switch name := strings.TrimPrefix(k, headerPrefix); name {
+		case "specversion":
+		case "id":
+			e.ID = v
+		case "source":
+			e.Source = v
+		case "type":
+			e.Type = v
+		case "subject":
+			e.Subject = v
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, err
+			}
+			e.Time = t
+		default:
+			e.Extensions[name] = v
+		}
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Event) validate() error {
+	switch {
+	case len(e.ID) == 0:
+		return missing("id")
+	case len(e.Source) == 0:
+		return missing("source")
+	case len(e.Type) == 0:
+		return missing("type")
+	}
+	return nil
+}
+
+func missing(attribute string) error {
+	return fmt.Errorf("%w %s", ErrMissingAttribute, attribute)
+}
+
+// MarshalJSON encodes the event in the CloudEvents JSON format, JSON data is
+// embedded as data and any other content as data_base64.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(e.Extensions)+8)
+	for k, v := range e.Extensions {
+		m[k] = v
+	}
+	m["specversion"] = SpecVersion
+	m["id"] = e.ID
+	m["source"] = e.Source
+	m["type"] = e.Type
+	if len(e.Subject) > 0 {
+		m["subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		m["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+	if len(e.DataContentType) > 0 {
+		m["datacontenttype"] = e.DataContentType
+	}
+	if len(e.Data) > 0 {
+		if isJSON(e.DataContentType) && json.Valid(e.Data) {
+			m["data"] = json.RawMessage(e.Data)
+		} else {
+			m["data_base64"] = base64.StdEncoding.EncodeToString(e.Data)
+		}
+	}
+	return json.Marshal(m)
+}
+
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if _, ok := m["specversion"]; !ok {
+		return missing("specversion")
+	}
+	*e = Event{Extensions: map[string]string{}}
+	for k, raw := range m {
+		switch k {
+		case "data":
+			e.Data = []byte(raw)
+			continue
+		case "data_base64":
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return err
+			}
+			e.Data = data
+			continue
+		}
+
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			// extension values may be numbers or booleans
+			v = string(raw)
+		}
+		switch k {
+		case "specversion":
+			if v != SpecVersion {
+				return ErrInvalidVersion
+			}
+		case "id":
+			e.ID = v
+		case "source":
+			e.Source = v
+		case "type":
+			e.Type = v
+		case "subject":
+			e.Subject = v
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return err
+			}
+			e.Time = t
+		case "datacontenttype":
+			e.DataContentType = v
+		default:
+			e.Extensions[k] = v
+		}
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	return len(contentType) == 0 || strings.HasPrefix(contentType, "application/json") || strings.HasSuffix(contentType, "+json")
+}
+
+type eventKey struct{}
+
+// NewContext puts the received event into ctx.
+func NewContext(ctx context.Context, e *Event) context.Context {
+	return context.WithValue(ctx, eventKey{}, e)
+}
+
+// FromContext returns the event being handled.
+func FromContext(ctx context.Context) (*Event, bool) {
+	e, ok := ctx.Value(eventKey{}).(*Event)
+	return e, ok
+}
@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nartvt/go-core/middleware/jwt"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+type orderCreated struct {
+	OrderId string `json:"order_id"`
+}
+
+func newContext(t *testing.T) context.Context {
+	traceId, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.Nil(t, err)
+	spanId, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.Nil(t, err)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId, TraceFlags: trace.FlagsSampled})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	return jwt.NewContext(ctx, jwtlib.MapClaims{"sub": "user-1"})
+}
+
+func TestEvent_BinaryRoundTrip(t *testing.T) {
+	e, err := New(newContext(t), "/orders", "order.created", orderCreated{OrderId: "o-1"})
+	require.Nil(t, err)
+
+	msg := e.Binary()
+	require.Equal(t, "1.0", msg.Header.Get("ce-specversion"))
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", msg.Header.Get("ce-traceparent"))
+
+	got, err := FromMessage(msg)
+	require.Nil(t, err)
+	require.Equal(t, e.ID, got.ID)
+	require.Equal(t, "order.created", got.Type)
+	require.Equal(t, "user-1", got.AuthID())
+	require.True(t, e.Time.Equal(got.Time))
+
+	var v orderCreated
+	require.Nil(t, got.DataAs(&v))
+	require.Equal(t, "o-1", v.OrderId)
+
+	sc := trace.SpanContextFromContext(got.Extract(context.Background()))
+	require.True(t, sc.IsRemote())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+}
+
+func TestEvent_StructuredRoundTrip(t *testing.T) {
+	e, err := New(newContext(t), "/orders", "order.created", orderCreated{OrderId: "o-1"})
+	require.Nil(t, err)
+
+	msg, err := e.Structured()
+	require.Nil(t, err)
+	require.Equal(t, ContentTypeStructured, msg.Header.Get("content-type"))
+	require.Contains(t, string(msg.Data), `"data":{"order_id":"o-1"}`)
+
+	got, err := FromMessage(msg)
+	require.Nil(t, err)
+	require.Equal(t, e.ID, got.ID)
+	require.Equal(t, e.Extensions, got.Extensions)
+	require.JSONEq(t, `{"order_id":"o-1"}`, string(got.Data))
+}
+
+func TestFromMessage_RequiredAttributes(t *testing.T) {
+	e, err := New(context.Background(), "/orders", "order.created", orderCreated{OrderId: "o-1"})
+	require.Nil(t, err)
+
+	for _, attribute := range []string{"id", "source", "type"} {
+		msg := e.Binary()
+		msg.Header.Del("ce-" + attribute)
+		_, err := FromMessage(msg)
+		require.ErrorIs(t, err, ErrMissingAttribute)
+		require.Contains(t, err.Error(), attribute)
+	}
+	msg := e.Binary()
+	msg.Header.Del("ce-specversion")
+	_, err = FromMessage(msg)
+	require.ErrorIs(t, err, ErrNotEvent)
+
+	for _, structured := range []string{
+		`{"id":"1","source":"/orders","type":"order.created"}`,
+		`{"specversion":"1.0","source":"/orders","type":"order.created"}`,
+		`{"specversion":"1.0","id":"1","type":"order.created"}`,
+		`{"specversion":"1.0","id":"1","source":"/orders"}`,
+	} {
+		msg := pubsub.NewMessage([]byte(structured))
+		msg.Header.Set("content-type", ContentTypeStructured)
+		_, err := FromMessage(msg)
+		require.ErrorIs(t, err, ErrMissingAttribute, structured)
+	}
+}
@@ -0,0 +1,59 @@
+package event
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+const tracerName = "github.com/nartvt/go-core/pubsub/event"
+
+// Publish publishes e in binary mode.
+func Publish(ctx context.Context, p pubsub.Publisher, topic string, e *Event) error {
+	return p.Publish(ctx, topic, e.Binary())
+}
+
+// Handler decodes received events and runs handler in a consumer span whose parent
+// is the span of the publisher. Messages that are not events are rejected as bad requests.
+func Handler(handler func(ctx context.Context, e *Event) error) pubsub.Handler {
+	tracer := otel.Tracer(tracerName)
+	return func(ctx context.Context, msg *pubsub.Message) error {
+		e, err := FromMessage(msg)
+		if err != nil {
+			return uerror.BadRequestError(err.Error())
+		}
+		ctx, span := tracer.Start(e.Extract(ctx), msg.Subject+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.destination.name", msg.Subject),
+				attribute.String("messaging.message.id", e.ID),
+				attribute.String("cloudevents.event_type", e.Type),
+				attribute.String("cloudevents.event_source", e.Source),
+			),
+		)
+		defer span.End()
+		if err := handler(ctx, e); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		return nil
+	}
+}
+
+// Typed is Handler with the event data decoded into T by the codec of its content type.
+func Typed[T any](handler func(ctx context.Context, e *Event, v T) error) pubsub.Handler {
+	return Handler(func(ctx context.Context, e *Event) error {
+		v, err := pubsub.DecodeValue[T](&pubsub.Message{Header: pubsub.Header{headerContentType: {e.DataContentType}}, Data: e.Data})
+		if err != nil {
+			return uerror.BadRequestError(err.Error())
+		}
+		return handler(ctx, e, v)
+	})
+}
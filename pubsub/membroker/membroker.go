@@ -0,0 +1,241 @@
+// Package membroker is an in-process pub/sub broker for tests. It implements
+// pubsub.Publisher and pubsub.Subscriber with NATS subject wildcards and queue
+// groups, and records every published message.
+package membroker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+var (
+	_ pubsub.Publisher  = (*Broker)(nil)
+	_ pubsub.Subscriber = (*Broker)(nil)
+)
+
+// ErrClosed is returned when publishing or subscribing on a closed broker.
+var ErrClosed = errors.New("membroker: broker closed")
+
+// Option is broker option.
+type Option func(*Broker)
+
+// Sync delivers messages in the publishing goroutine, Publish returns once every
+// handler ran and reports their errors. As on a real broker handlers do not get the
+// publisher context, its values, deadline and cancellation do not reach them.
+func Sync() Option {
+	return func(b *Broker) {
+		b.sync = true
+	}
+}
+
+// Record is a published message.
+type Record struct {
+	Topic   string
+	Message *pubsub.Message
+}
+
+type subscription struct {
+	subject    string
+	queueGroup string
+	handler    pubsub.Handler
+}
+
+// Broker is an in-memory broker, by default handlers run in their own goroutine like on NATS.
+type Broker struct {
+	sync bool
+
+	mu        sync.Mutex
+	subs      []*subscription
+	next      map[string]int // round robin position per subject and queue group
+	published []Record
+	errs      []error
+	closed    bool
+	running   int // handlers running in their own goroutine
+	idle      *sync.Cond
+}
+
+func New(opts ...Option) *Broker {
+	b := &Broker{next: map[string]int{}}
+	b.idle = sync.NewCond(&b.mu)
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish records msg and delivers it to every matching subscription, members
+// of a queue group share the messages.
+func (b *Broker) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	// the caller may reuse msg, the record keeps its own copy
+	b.published = append(b.published, Record{Topic: topic, Message: copyMessage(topic, msg)})
+	targets := b.route(topic)
+	if !b.sync {
+		b.running += len(targets)
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for _, sub := range targets {
+		m := copyMessage(topic, msg)
+		if b.sync {
+			if err := b.deliver(context.Background(), sub, m); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		go func(sub *subscription) {
+			defer b.done()
+			_ = b.deliver(context.Background(), sub, m)
+		}(sub)
+	}
+	return errors.Join(errs...)
+}
+
+// route returns the subscriptions receiving a message on topic, b.mu must be held.
+func (b *Broker) route(topic string) []*subscription {
+	var targets []*subscription
+	groups := map[string][]*subscription{}
+	var order []string
+	for _, sub := range b.subs {
+		if !pubsub.MatchSubject(sub.subject, topic) {
+			continue
+		}
+		if len(sub.queueGroup) == 0 {
+			targets = append(targets, sub)
+			continue
+		}
+		key := sub.subject + " " + sub.queueGroup
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+	for _, key := range order {
+		members := groups[key]
+		i := b.next[key] % len(members)
+		b.next[key] = i + 1
+		targets = append(targets, members[i])
+	}
+	return targets
+}
+
+func (b *Broker) deliver(ctx context.Context, sub *subscription, msg *pubsub.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = uerror.PanicError(r)
+		}
+		if err != nil {
+			b.mu.Lock()
+			b.errs = append(b.errs, err)
+			b.mu.Unlock()
+		}
+	}()
+	return sub.handler(ctx, msg)
+}
+
+func (b *Broker) Subscribe(subject, queueGroup string, handler pubsub.Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.subs = append(b.subs, &subscription{subject: subject, queueGroup: queueGroup, handler: handler})
+	return nil
+}
+
+// Unsubscribe removes the handlers of subject and queueGroup, running handlers complete.
+func (b *Broker) Unsubscribe(subject, queueGroup string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.subs[:0]
+	for _, sub := range b.subs {
+		if sub.subject != subject || sub.queueGroup != queueGroup {
+			subs = append(subs, sub)
+		}
+	}
+	b.subs = subs
+	return nil
+}
+
+// Wait blocks until the handlers of messages published so far returned.
+func (b *Broker) Wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.running > 0 {
+		b.idle.Wait()
+	}
+}
+
+func (b *Broker) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.running--
+	if b.running == 0 {
+		b.idle.Broadcast()
+	}
+}
+
+// Published returns the messages published so far.
+func (b *Broker) Published() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Record(nil), b.published...)
+}
+
+// Messages returns the messages published on topic, topic may contain wildcards.
+func (b *Broker) Messages(topic string) []*pubsub.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var msgs []*pubsub.Message
+	for _, r := range b.published {
+		if pubsub.MatchSubject(topic, r.Topic) {
+			msgs = append(msgs, r.Message)
+		}
+	}
+	return msgs
+}
+
+// Errors returns the errors returned by handlers.
+func (b *Broker) Errors() []error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]error(nil), b.errs...)
+}
+
+// Reset forgets the recorded messages and errors, subscriptions are kept.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = nil
+	b.errs = nil
+}
+
+// Close waits for running handlers, later publishes fail with ErrClosed.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.subs = nil
+	b.mu.Unlock()
+	b.Wait()
+	return nil
+}
+
+func copyMessage(topic string, msg *pubsub.Message) *pubsub.Message {
+	header := make(pubsub.Header, len(msg.Header))
+	for k, v := range msg.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &pubsub.Message{Subject: topic, Header: header, Data: append([]byte(nil), msg.Data...)}
+}
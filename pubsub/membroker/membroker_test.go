@@ -0,0 +1,116 @@
+package membroker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func TestBroker_WildcardsAndQueueGroups(t *testing.T) {
+	ctx := context.Background()
+	b := New(Sync())
+
+	var all, created []string
+	workers := map[string]int{}
+	require.Nil(t, b.Subscribe("orders.>", "", func(ctx context.Context, msg *pubsub.Message) error {
+		all = append(all, msg.Subject)
+		return nil
+	}))
+	require.Nil(t, b.Subscribe("orders.*.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		created = append(created, msg.Subject)
+		return nil
+	}))
+	for _, name := range []string{"w1", "w2"} {
+		name := name
+		require.Nil(t, b.Subscribe("orders.>", "workers", func(ctx context.Context, msg *pubsub.Message) error {
+			workers[name]++
+			return nil
+		}))
+	}
+
+	require.Nil(t, b.Publish(ctx, "orders.eu.created", pubsub.NewMessage([]byte("1"))))
+	require.Nil(t, b.Publish(ctx, "orders.eu.paid", pubsub.NewMessage([]byte("2"))))
+	require.Nil(t, b.Publish(ctx, "payments.eu", pubsub.NewMessage([]byte("3"))))
+
+	require.Equal(t, []string{"orders.eu.created", "orders.eu.paid"}, all)
+	require.Equal(t, []string{"orders.eu.created"}, created)
+	require.Equal(t, map[string]int{"w1": 1, "w2": 1}, workers)
+	require.Len(t, b.Published(), 3)
+	require.Len(t, b.Messages("orders.>"), 2)
+}
+
+func TestBroker_SyncReturnsHandlerErrors(t *testing.T) {
+	b := New(Sync())
+	errFailed := errors.New("failed")
+	require.Nil(t, pubsub.Subscribe(b, "orders.created", "", func(ctx context.Context, v map[string]string) error {
+		return errFailed
+	}))
+
+	require.ErrorIs(t, pubsub.Send(context.Background(), b, "orders.created", map[string]string{"id": "o-1"}), errFailed)
+	require.Equal(t, []error{errFailed}, b.Errors())
+}
+
+func TestBroker_Async(t *testing.T) {
+	b := New()
+	received := make(chan string, 1)
+	require.Nil(t, b.Subscribe("orders.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		received <- string(msg.Data)
+		return nil
+	}))
+
+	require.Nil(t, b.Publish(context.Background(), "orders.created", pubsub.NewMessage([]byte("o-1"))))
+	b.Wait()
+	require.Equal(t, "o-1", <-received)
+	require.Nil(t, b.Close())
+	require.Equal(t, ErrClosed, b.Publish(context.Background(), "orders.created", pubsub.NewMessage(nil)))
+}
+
+func TestBroker_SyncDoesNotShareThePublisherContext(t *testing.T) {
+	type key struct{}
+	b := New(Sync())
+	require.Nil(t, b.Subscribe("orders.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		if ctx.Value(key{}) != nil {
+			return errors.New("handler got the publisher context")
+		}
+		return nil
+	}))
+	ctx := context.WithValue(context.Background(), key{}, "publisher")
+	require.Nil(t, b.Publish(ctx, "orders.created", pubsub.NewMessage(nil)))
+}
+
+func TestBroker_RecordsACopy(t *testing.T) {
+	b := New()
+	msg := pubsub.NewMessage([]byte("o-1"))
+	msg.Header.Set("Key", "a")
+	require.Nil(t, b.Publish(context.Background(), "orders.created", msg))
+	msg.Data[0] = 'x'
+	msg.Header.Set("Key", "b")
+
+	recorded := b.Messages("orders.created")
+	require.Len(t, recorded, 1)
+	require.Equal(t, "o-1", string(recorded[0].Data))
+	require.Equal(t, "a", recorded[0].Header.Get("Key"))
+}
+
+func TestBroker_WaitWhilePublishing(t *testing.T) {
+	b := New()
+	require.Nil(t, b.Subscribe("orders.*", "", func(ctx context.Context, msg *pubsub.Message) error { return nil }))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = b.Publish(context.Background(), "orders.created", pubsub.NewMessage(nil))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		b.Wait()
+	}
+	<-done
+	require.Nil(t, b.Close())
+	require.Len(t, b.Published(), 100)
+}
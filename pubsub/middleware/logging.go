@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// Logging logs every published and consumed message with its latency and error.
+func Logging(logger log.Logger) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, direction, ok := fromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			reply, err := handler(ctx, req)
+
+			level := log.LevelInfo
+			if err != nil {
+				level = log.LevelError
+			}
+			size := 0
+			if msg, ok := req.(*pubsub.Message); ok {
+				size = len(msg.Data)
+			}
+			code, reason := status(err)
+			_ = log.WithContext(ctx, logger).Log(level,
+				"kind", tr.Kind(),
+				"direction", direction,
+				"subject", tr.Operation(),
+				"bytes", size,
+				"code", code,
+				"reason", reason,
+				"error", errString(err),
+				"latency", time.Since(start).Seconds(),
+			)
+			return reply, err
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
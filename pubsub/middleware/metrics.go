@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/go-kratos/kratos/v2/middleware"
+)
+
+// MetricsOption is metrics option.
+type MetricsOption func(*metricsOptions)
+
+// WithRequests with messages counter, labels are {direction, subject, code, reason}.
+func WithRequests(c metrics.Counter) MetricsOption {
+	return func(o *metricsOptions) {
+		o.requests = c
+	}
+}
+
+// WithSeconds with latency histogram, labels are {direction, subject}.
+func WithSeconds(o metrics.Observer) MetricsOption {
+	return func(opts *metricsOptions) {
+		opts.seconds = o
+	}
+}
+
+type metricsOptions struct {
+	requests metrics.Counter
+	seconds  metrics.Observer
+}
+
+// Metrics counts published and consumed messages and observes their latency.
+func Metrics(opts ...MetricsOption) middleware.Middleware {
+	o := &metricsOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, direction, ok := fromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			start := time.Now()
+			reply, err := handler(ctx, req)
+			code, reason := status(err)
+			if o.requests != nil {
+				o.requests.With(direction, tr.Operation(), strconv.Itoa(code), reason).Inc()
+			}
+			if o.seconds != nil {
+				o.seconds.With(direction, tr.Operation()).Observe(time.Since(start).Seconds())
+			}
+			return reply, err
+		}
+	}
+}
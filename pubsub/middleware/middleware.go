@@ -0,0 +1,47 @@
+// Package middleware provides kratos middleware for the publish and consume paths of
+// pubsub. Use them with pubsub.WrapPublisher, pubsub.WrapSubscriber or the NATS server.
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+// Directions of a message.
+const (
+	DirectionPublish = "publish"
+	DirectionConsume = "consume"
+)
+
+// fromContext returns the message transport and direction. The client transport is
+// checked first, so a handler publishing from a consume context is seen as a publish.
+func fromContext(ctx context.Context) (transport.Transporter, string, bool) {
+	if tr, ok := transport.FromClientContext(ctx); ok {
+		if _, ok := tr.(*pubsub.Transport); ok {
+			return tr, DirectionPublish, true
+		}
+	}
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		return tr, DirectionConsume, true
+	}
+	return nil, "", false
+}
+
+// status returns the code and reason of err, 0 when err is nil.
+func status(err error) (int, string) {
+	if err == nil {
+		return 0, ""
+	}
+	var se *uerror.StatusError
+	if errors.As(err, &se) {
+		return int(se.Code), se.Key
+	}
+	ke := kerrors.FromError(err)
+	return int(ke.Code), ke.Reason
+}
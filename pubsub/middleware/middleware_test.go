@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/pubsub/membroker"
+	"github.com/nartvt/go-core/uerror"
+)
+
+type counter struct {
+	labels [][]string
+}
+
+func (c *counter) With(labels ...string) metrics.Counter {
+	c.labels = append(c.labels, labels)
+	return c
+}
+
+func (c *counter) Inc() {}
+
+func (c *counter) Add(float64) {}
+
+func TestMiddleware_PublishAndConsume(t *testing.T) {
+	broker := membroker.New(membroker.Sync())
+	requests := &counter{}
+	tracing := Tracing(WithPropagator(propagation.TraceContext{}))
+	publisher := pubsub.WrapPublisher(broker, tracing, Metrics(WithRequests(requests)))
+	subscriber := pubsub.WrapSubscriber(broker, tracing, Metrics(WithRequests(requests)))
+
+	var received trace.SpanContext
+	require.Nil(t, subscriber.Subscribe("orders.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		received = trace.SpanContextFromContext(ctx)
+		return uerror.NotFoundError("order not found")
+	}))
+
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	err := publisher.Publish(ctx, "orders.created", pubsub.NewMessage([]byte("o-1")))
+	require.Error(t, err)
+
+	require.Equal(t, traceId, received.TraceID())
+	require.True(t, received.IsRemote())
+	require.Equal(t, [][]string{
+		{DirectionConsume, "orders.created", "404", uerror.NOT_FOUND},
+		{DirectionPublish, "orders.created", "404", uerror.NOT_FOUND},
+	}, requests.labels)
+}
+
+type recordLogger struct {
+	records []map[string]interface{}
+}
+
+func (l *recordLogger) Log(level log.Level, keyvals ...interface{}) error {
+	r := map[string]interface{}{"level": level}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		r[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.records = append(l.records, r)
+	return nil
+}
+
+func TestLogging(t *testing.T) {
+	broker := membroker.New(membroker.Sync())
+	logger := &recordLogger{}
+	publisher := pubsub.WrapPublisher(broker, Logging(logger))
+	subscriber := pubsub.WrapSubscriber(broker, Logging(logger))
+
+	require.Nil(t, subscriber.Subscribe("orders.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		// published from the consume context, logged as a publish
+		return publisher.Publish(ctx, "orders.audit", pubsub.NewMessage([]byte("audit")))
+	}))
+	require.Nil(t, subscriber.Subscribe("orders.audit", "", func(ctx context.Context, msg *pubsub.Message) error {
+		return uerror.NotFoundError("order not found")
+	}))
+	require.Error(t, publisher.Publish(context.Background(), "orders.created", pubsub.NewMessage([]byte("o-1"))))
+
+	type entry struct {
+		direction, subject string
+		code               int
+		level              log.Level
+	}
+	var got []entry
+	for _, r := range logger.records {
+		got = append(got, entry{r["direction"].(string), r["subject"].(string), r["code"].(int), r["level"].(log.Level)})
+	}
+	require.Equal(t, []entry{
+		{DirectionConsume, "orders.audit", 404, log.LevelError},
+		{DirectionPublish, "orders.audit", 404, log.LevelError},
+		{DirectionConsume, "orders.created", 404, log.LevelError},
+		{DirectionPublish, "orders.created", 404, log.LevelError},
+	}, got)
+	require.Equal(t, 5, logger.records[1]["bytes"])
+	require.Equal(t, uerror.NOT_FOUND, logger.records[1]["reason"])
+	require.Equal(t, "order not found", logger.records[1]["error"])
+
+	logger.records = nil
+	_, err := Logging(logger)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(context.Background(), pubsub.NewMessage(nil))
+	require.Nil(t, err)
+	require.Empty(t, logger.records, "calls without a pubsub transport are not logged")
+}
+
+type order struct {
+	ID string
+}
+
+func (o order) Validate() error {
+	if len(o.ID) == 0 {
+		return errors.New("invalid Order.ID: value length must be at least 1 runes")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "handled", nil
+	}
+	handler := Validate()(next)
+
+	reply, err := handler(context.Background(), order{ID: "o-1"})
+	require.Nil(t, err)
+	require.Equal(t, "handled", reply)
+
+	_, err = handler(context.Background(), order{})
+	require.True(t, pubsub.IsTerminal(err))
+	var se *uerror.StatusError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, int64(http.StatusBadRequest), se.Code)
+
+	untyped := pubsub.NewMessage([]byte("{}"))
+	unknown := pubsub.NewMessage([]byte("{}"))
+	unknown.Header.Set(pubsub.MessageTypeHeader, "orders.v1.Unknown")
+	withoutRules, err := pubsub.Encode("proto", &conf.Redis{Addr: "127.0.0.1:6379"})
+	require.Nil(t, err)
+	for _, msg := range []*pubsub.Message{untyped, unknown, withoutRules} {
+		reply, err := handler(context.Background(), msg)
+		require.Nil(t, err)
+		require.Equal(t, "handled", reply)
+	}
+}
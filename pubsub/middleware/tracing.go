@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+const tracerName = "github.com/nartvt/go-core/pubsub"
+
+// TracingOption is tracing option.
+type TracingOption func(*tracingOptions)
+
+// WithTracerProvider with tracer provider, the global provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) TracingOption {
+	return func(o *tracingOptions) {
+		o.provider = provider
+	}
+}
+
+// WithPropagator with propagator, the global propagator is used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) TracingOption {
+	return func(o *tracingOptions) {
+		o.propagator = propagator
+	}
+}
+
+type tracingOptions struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// Tracing starts a producer span around publishing and injects its context into the
+// message header, and a consumer span around handlers whose parent is extracted from it.
+func Tracing(opts ...TracingOption) middleware.Middleware {
+	o := &tracingOptions{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	tracer := o.provider.Tracer(tracerName)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, direction, ok := fromContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			kind, operation := trace.SpanKindProducer, "publish"
+			if direction == DirectionConsume {
+				kind, operation = trace.SpanKindConsumer, "process"
+				ctx = o.propagator.Extract(ctx, tr.RequestHeader())
+			}
+			attrs := []attribute.KeyValue{
+				attribute.String("messaging.system", string(tr.Kind())),
+				attribute.String("messaging.destination.name", tr.Operation()),
+				attribute.String("messaging.operation", operation),
+			}
+			if msg, ok := req.(*pubsub.Message); ok {
+				attrs = append(attrs, attribute.Int("messaging.message.body.size", len(msg.Data)))
+			}
+			ctx, span := tracer.Start(ctx, tr.Operation()+" "+operation, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+			defer span.End()
+			if direction == DirectionPublish {
+				o.propagator.Inject(ctx, tr.RequestHeader())
+			}
+
+			reply, err := handler(ctx, req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return reply, err
+		}
+	}
+}
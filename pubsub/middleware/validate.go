@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+type validator interface {
+	Validate() error
+}
+
+// Validate checks proto messages generated with protoc-gen-validate. The message type
+// is resolved from the pubsub.MessageTypeHeader set by pubsub.Encode, messages without
+// it are passed through. Invalid messages are rejected with a bad request error, which
+// consumers treat as terminal.
+func Validate() middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := validate(req); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+func validate(req interface{}) error {
+	if v, ok := req.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return uerror.BadRequestError(err.Error())
+		}
+		return nil
+	}
+	msg, ok := req.(*pubsub.Message)
+	if !ok {
+		return nil
+	}
+	name := msg.Header.Get(pubsub.MessageTypeHeader)
+	if len(name) == 0 {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		// the type is not linked in this binary, nothing to validate against
+		return nil
+	}
+	m := mt.New().Interface()
+	if _, ok := m.(validator); !ok {
+		return nil
+	}
+	if err := pubsub.Decode(msg, m); err != nil {
+		return uerror.BadRequestError(err.Error())
+	}
+	if err := m.(validator).Validate(); err != nil {
+		return uerror.BadRequestError(err.Error())
+	}
+	return nil
+}
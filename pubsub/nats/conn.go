@@ -0,0 +1,46 @@
+package nats
+
+import (
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+)
+
+// Connect opens a NATS connection from config.
+func Connect(c *conf.Nats, opts ...nats.Option) (*nats.Conn, error) {
+	var options []nats.Option
+	if len(c.Name) > 0 {
+		options = append(options, nats.Name(c.Name))
+	}
+	if len(c.User) > 0 {
+		options = append(options, nats.UserInfo(c.User, c.Pass))
+	}
+	if len(c.Token) > 0 {
+		options = append(options, nats.Token(c.Token))
+	}
+	if c.Timeout != nil {
+		options = append(options, nats.Timeout(c.Timeout.AsDuration()))
+	}
+	if c.MaxReconnects != 0 {
+		options = append(options, nats.MaxReconnects(int(c.MaxReconnects)))
+	}
+	if c.ReconnectWait != nil {
+		options = append(options, nats.ReconnectWait(c.ReconnectWait.AsDuration()))
+	}
+	addr := c.Addr
+	if len(addr) == 0 {
+		addr = nats.DefaultURL
+	}
+	return nats.Connect(addr, append(options, opts...)...)
+}
+
+func toNatsHeader(h map[string][]string) nats.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	header := make(nats.Header, len(h))
+	for k, v := range h {
+		header[k] = v
+	}
+	return header
+}
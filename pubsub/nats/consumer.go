@@ -0,0 +1,258 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+// Headers set on messages routed to the dead-letter subject.
+const (
+	DeadLetterErrorHeader      = "Dead-Letter-Error"
+	DeadLetterSubjectHeader    = "Dead-Letter-Subject"
+	DeadLetterStreamHeader     = "Dead-Letter-Stream"
+	DeadLetterConsumerHeader   = "Dead-Letter-Consumer"
+	DeadLetterDeliveriesHeader = "Dead-Letter-Deliveries"
+)
+
+const (
+	defaultBackoffInitial = time.Second
+	defaultBackoffMax     = time.Minute
+	defaultAckWait        = 30 * time.Second
+)
+
+var _ transport.Server = (*Consumer)(nil)
+
+// ConsumerOption is JetStream consumer option.
+type ConsumerOption func(*Consumer)
+
+// WithConsumerLogger with consumer logger.
+func WithConsumerLogger(logger log.Logger) ConsumerOption {
+	return func(c *Consumer) {
+		c.log = log.NewHelper(logger)
+	}
+}
+
+// WithConsumerMiddleware with middleware running around the handler.
+func WithConsumerMiddleware(m ...middleware.Middleware) ConsumerOption {
+	return func(c *Consumer) {
+		c.middleware = m
+	}
+}
+
+// Consumer is a pull based durable JetStream consumer. The handler error decides
+// the outcome: nil acks, a terminal error (see pubsub.IsTerminal) or the last
+// allowed delivery routes the message to the dead-letter subject, any other
+// error naks it with an exponential redelivery delay.
+type Consumer struct {
+	conf       *conf.Nats_Consumer
+	js         jetstream.JetStream
+	handler    pubsub.Handler
+	log        *log.Helper
+	middleware []middleware.Middleware
+
+	mu      sync.Mutex
+	cc      jetstream.ConsumeContext
+	stopped bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func NewConsumer(js jetstream.JetStream, c *conf.Nats_Consumer, handler pubsub.Handler, opts ...ConsumerOption) *Consumer {
+	consumer := &Consumer{
+		conf:    c,
+		js:      js,
+		handler: handler,
+		log:     log.NewHelper(log.GetLogger()),
+	}
+	for _, opt := range opts {
+		opt(consumer)
+	}
+	return consumer
+}
+
+// Start creates or updates the durable consumer and starts pulling messages.
+func (c *Consumer) Start(ctx context.Context) error {
+	cons, err := c.js.CreateOrUpdateConsumer(ctx, c.conf.Stream, c.consumerConfig())
+	if err != nil {
+		return fmt.Errorf("create consumer %s: %w", c.conf.Durable, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.stopped = false
+	// the durable consumer balances messages like a queue group
+	handler := wrapHandler(c.conf.Subject, c.conf.Durable, c.middleware, c.handler)
+	var opts []jetstream.PullConsumeOpt
+	if c.conf.BatchSize > 0 {
+		opts = append(opts, jetstream.PullMaxMessages(int(c.conf.BatchSize)))
+	}
+	cc, err := cons.Consume(func(m jetstream.Msg) {
+		// counted under the lock, so Stop never waits while a handler is being added
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			_ = m.Nak()
+			return
+		}
+		c.wg.Add(1)
+		c.mu.Unlock()
+		defer c.wg.Done()
+		c.process(handler, m)
+	}, opts...)
+	if err != nil {
+		c.cancel()
+		return err
+	}
+	c.cc = cc
+	c.log.Infof("[NATS] consumer %s started on stream %s", c.conf.Durable, c.conf.Stream)
+	return nil
+}
+
+// Stop stops pulling and waits for the running handler, messages already pulled
+// but not yet handled are nak'ed.
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cc := c.cc
+	c.cc = nil
+	c.stopped = true
+	c.mu.Unlock()
+	if cc == nil {
+		return nil
+	}
+	cc.Stop()
+	c.wg.Wait()
+	c.cancel()
+	c.log.Infof("[NATS] consumer %s stopped", c.conf.Durable)
+	return nil
+}
+
+func (c *Consumer) consumerConfig() jetstream.ConsumerConfig {
+	cfg := jetstream.ConsumerConfig{
+		Durable:       c.conf.Durable,
+		FilterSubject: c.conf.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxDeliver:    int(c.conf.MaxDeliver),
+		AckWait:       c.ackWait(),
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = -1
+	}
+	return cfg
+}
+
+func (c *Consumer) ackWait() time.Duration {
+	if c.conf.AckWait != nil {
+		return c.conf.AckWait.AsDuration()
+	}
+	return defaultAckWait
+}
+
+func (c *Consumer) process(handler pubsub.Handler, m jetstream.Msg) {
+	var delivered uint64 = 1
+	if meta, err := m.Metadata(); err == nil {
+		delivered = meta.NumDelivered
+	}
+
+	err := c.handle(handler, m)
+	var ackErr error
+	switch {
+	case err == nil:
+		ackErr = m.Ack()
+	case pubsub.IsTerminal(err) || c.exhausted(delivered):
+		c.log.Errorw("msg", "nats message dead-lettered", "subject", m.Subject(), "deliveries", delivered, "error", err)
+		ackErr = c.deadLetter(m, delivered, err)
+	default:
+		c.log.Warnw("msg", "nats handler failed, retrying", "subject", m.Subject(), "deliveries", delivered, "error", err)
+		ackErr = m.NakWithDelay(c.backoff(delivered))
+	}
+	if ackErr != nil {
+		c.log.Errorw("msg", "nats ack failed", "subject", m.Subject(), "error", ackErr)
+	}
+}
+
+func (c *Consumer) handle(handler pubsub.Handler, m jetstream.Msg) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = uerror.PanicError(r)
+		}
+	}()
+	// give up before the server redelivers the message to another consumer
+	ctx, cancel := context.WithTimeout(c.ctx, c.ackWait())
+	defer cancel()
+
+	header := pubsub.Header(m.Headers())
+	if header == nil {
+		header = pubsub.Header{}
+	}
+	return handler(ctx, &pubsub.Message{Subject: m.Subject(), Header: header, Data: m.Data()})
+}
+
+func (c *Consumer) exhausted(delivered uint64) bool {
+	return c.conf.MaxDeliver > 0 && delivered >= uint64(c.conf.MaxDeliver)
+}
+
+// backoff returns backoff_initial * 2^(delivered-1) capped at backoff_max.
+func (c *Consumer) backoff(delivered uint64) time.Duration {
+	initial, max := defaultBackoffInitial, defaultBackoffMax
+	if c.conf.BackoffInitial != nil {
+		initial = c.conf.BackoffInitial.AsDuration()
+	}
+	if c.conf.BackoffMax != nil {
+		max = c.conf.BackoffMax.AsDuration()
+	}
+	delay := initial
+	for i := uint64(1); i < delivered && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// deadLetter publishes the message with error metadata to the dead-letter subject and
+// terminates it. Without a dead-letter subject the message is only terminated. When
+// publishing fails the message is left unacked: the server redelivers it after ack_wait,
+// or keeps it in the stream once max_deliver is reached, instead of dropping it.
+func (c *Consumer) deadLetter(m jetstream.Msg, delivered uint64, cause error) error {
+	if len(c.conf.DeadLetterSubject) == 0 {
+		return m.Term()
+	}
+	header := nats.Header{}
+	for k, v := range m.Headers() {
+		header[k] = v
+	}
+	// the dead-letter copy must not be dropped as a duplicate of the original
+	header.Del(MsgIDHeader)
+	header.Set(DeadLetterErrorHeader, cause.Error())
+	header.Set(DeadLetterSubjectHeader, m.Subject())
+	header.Set(DeadLetterStreamHeader, c.conf.Stream)
+	header.Set(DeadLetterConsumerHeader, c.conf.Durable)
+	header.Set(DeadLetterDeliveriesHeader, strconv.FormatUint(delivered, 10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultAckTimeout)
+	defer cancel()
+	if _, err := c.js.PublishMsg(ctx, &nats.Msg{Subject: c.conf.DeadLetterSubject, Header: header, Data: m.Data()}); err != nil {
+		c.log.Errorw("msg", "nats dead-letter publish failed, message left unacked",
+			"subject", m.Subject(), "dead_letter_subject", c.conf.DeadLetterSubject,
+			"stream", c.conf.Stream, "consumer", c.conf.Durable, "deliveries", delivered,
+			"cause", cause, "error", err)
+		return nil
+	}
+	return m.Term()
+}
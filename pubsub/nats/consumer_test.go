@@ -0,0 +1,164 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// fakeMsg records how a JetStream message was settled.
+type fakeMsg struct {
+	jetstream.Msg
+	delivered uint64
+	outcome   string
+	delay     time.Duration
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+func (m *fakeMsg) Data() []byte         { return []byte(`{"id":1}`) }
+func (m *fakeMsg) Headers() nats.Header { return nats.Header{MsgIDHeader: []string{"m1"}} }
+func (m *fakeMsg) Subject() string      { return "orders.created" }
+func (m *fakeMsg) Ack() error           { m.outcome = "ack"; return nil }
+func (m *fakeMsg) Term() error          { m.outcome = "term"; return nil }
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.outcome, m.delay = "nak", delay
+	return nil
+}
+
+// fakeJetStream records the dead-letter publishes.
+type fakeJetStream struct {
+	jetstream.JetStream
+	err       error
+	published []*nats.Msg
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestConsumer_Backoff(t *testing.T) {
+	c := NewConsumer(nil, &conf.Nats_Consumer{
+		MaxDeliver:     5,
+		BackoffInitial: durationpb.New(100 * time.Millisecond),
+		BackoffMax:     durationpb.New(time.Second),
+	}, nil)
+
+	require.Equal(t, 100*time.Millisecond, c.backoff(1))
+	require.Equal(t, 200*time.Millisecond, c.backoff(2))
+	require.Equal(t, 800*time.Millisecond, c.backoff(4))
+	require.Equal(t, time.Second, c.backoff(5))
+	require.Equal(t, time.Second, c.backoff(100))
+
+	require.False(t, c.exhausted(4))
+	require.True(t, c.exhausted(5))
+}
+
+func TestConsumer_Process(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		delivered  uint64
+		deadLetter string
+		publishErr error
+		outcome    string
+		delay      time.Duration
+		dlq        bool
+	}{
+		{name: "success acks", outcome: "ack", delivered: 1},
+		{name: "transient naks with backoff", err: errors.New("db down"), delivered: 2, outcome: "nak", delay: 200 * time.Millisecond},
+		{name: "server error naks", err: kerrors.InternalServer("INTERNAL", "boom"), delivered: 1, outcome: "nak", delay: 100 * time.Millisecond},
+		{name: "terminal is dead-lettered", err: pubsub.Terminal(errors.New("bad payload")), delivered: 1, deadLetter: "orders.dlq", outcome: "term", dlq: true},
+		{name: "client error is dead-lettered", err: kerrors.BadRequest("INVALID", "bad"), delivered: 1, deadLetter: "orders.dlq", outcome: "term", dlq: true},
+		{name: "last delivery is dead-lettered", err: errors.New("db down"), delivered: 3, deadLetter: "orders.dlq", outcome: "term", dlq: true},
+		{name: "terminal without dead-letter subject terms", err: pubsub.Terminal(errors.New("bad payload")), delivered: 1, outcome: "term"},
+		{name: "failed dead-letter publish stays unacked", err: pubsub.Terminal(errors.New("bad payload")), delivered: 1, deadLetter: "orders.dlq", publishErr: errors.New("no stream")},
+		{name: "failed dead-letter publish on last delivery stays unacked", err: errors.New("db down"), delivered: 3, deadLetter: "orders.dlq", publishErr: errors.New("no stream")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			js := &fakeJetStream{err: tc.publishErr}
+			c := NewConsumer(js, &conf.Nats_Consumer{
+				Stream:            "ORDERS",
+				Durable:           "billing",
+				MaxDeliver:        3,
+				BackoffInitial:    durationpb.New(100 * time.Millisecond),
+				DeadLetterSubject: tc.deadLetter,
+			}, nil)
+			c.ctx = context.Background()
+			m := &fakeMsg{delivered: tc.delivered}
+
+			c.process(func(ctx context.Context, msg *pubsub.Message) error { return tc.err }, m)
+			require.Equal(t, tc.outcome, m.outcome)
+			require.Equal(t, tc.delay, m.delay)
+			if !tc.dlq {
+				require.Empty(t, js.published)
+				return
+			}
+			require.Len(t, js.published, 1)
+			dl := js.published[0]
+			require.Equal(t, "orders.dlq", dl.Subject)
+			require.Empty(t, dl.Header.Get(MsgIDHeader))
+			require.Equal(t, tc.err.Error(), dl.Header.Get(DeadLetterErrorHeader))
+			require.Equal(t, "orders.created", dl.Header.Get(DeadLetterSubjectHeader))
+			require.Equal(t, "ORDERS", dl.Header.Get(DeadLetterStreamHeader))
+			require.Equal(t, "billing", dl.Header.Get(DeadLetterConsumerHeader))
+			require.Equal(t, strconv.FormatUint(tc.delivered, 10), dl.Header.Get(DeadLetterDeliveriesHeader))
+		})
+	}
+}
+
+func TestConsumer_Transport(t *testing.T) {
+	srv := runServer(t)
+	conn, err := nats.Connect(srv.ClientURL())
+	require.Nil(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.Nil(t, err)
+	ctx := context.Background()
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}})
+	require.Nil(t, err)
+
+	seen := make(chan *Transport, 1)
+	capture := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, _ := transport.FromServerContext(ctx)
+			seen <- tr.(*Transport)
+			return next(ctx, req)
+		}
+	}
+	c := NewConsumer(js, &conf.Nats_Consumer{Stream: "ORDERS", Durable: "billing", Subject: "orders.*"},
+		func(ctx context.Context, msg *pubsub.Message) error { return nil },
+		WithConsumerMiddleware(capture))
+	require.Nil(t, c.Start(ctx))
+	defer c.Stop(ctx)
+
+	_, err = js.Publish(ctx, "orders.created", nil)
+	require.Nil(t, err)
+	select {
+	case tr := <-seen:
+		require.Equal(t, "orders.*", tr.Endpoint())
+		require.Equal(t, "orders.created", tr.Operation())
+		require.Equal(t, "billing", tr.QueueGroup())
+	case <-time.After(time.Second):
+		t.Fatal("message not consumed")
+	}
+}
@@ -0,0 +1,235 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// MsgIDHeader is the JetStream de-duplication header, a message published twice
+// with the same id inside the stream duplicate window is stored once.
+const MsgIDHeader = jetstream.MsgIDHeader
+
+const defaultAckTimeout = 5 * time.Second
+
+var _ pubsub.Publisher = (*JetStreamPublisher)(nil)
+
+// JetStreamPublisher publishes to JetStream streams and waits for the server acknowledgement.
+type JetStreamPublisher struct {
+	conn       *nats.Conn
+	owned      bool
+	js         jetstream.JetStream
+	ackTimeout time.Duration
+}
+
+// NewJetStreamPublisher connects to NATS and creates or validates the configured streams.
+func NewJetStreamPublisher(c *conf.Nats) (*JetStreamPublisher, error) {
+	conn, err := Connect(c)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewJetStreamPublisherWithConn(conn, c.Jetstream)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p.owned = true
+	return p, nil
+}
+
+// NewJetStreamPublisherWithConn publishes on a connection owned by the caller.
+func NewJetStreamPublisherWithConn(conn *nats.Conn, c *conf.Nats_JetStream) (*JetStreamPublisher, error) {
+	if c == nil {
+		c = &conf.Nats_JetStream{}
+	}
+	var opts []jetstream.JetStreamOpt
+	if c.MaxPending > 0 {
+		opts = append(opts, jetstream.WithPublishAsyncMaxPending(int(c.MaxPending)))
+	}
+	js, err := jetstream.New(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p := &JetStreamPublisher{
+		conn:       conn,
+		js:         js,
+		ackTimeout: defaultAckTimeout,
+	}
+	if c.AckTimeout != nil {
+		p.ackTimeout = c.AckTimeout.AsDuration()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.ackTimeout)
+	defer cancel()
+	if err := EnsureStreams(ctx, js, c); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EnsureStreams creates or updates the configured streams when CreateStreams is set,
+// otherwise it checks that every stream exists and covers its configured subjects.
+func EnsureStreams(ctx context.Context, js jetstream.JetStream, c *conf.Nats_JetStream) error {
+	for _, sc := range c.GetStreams() {
+		if c.CreateStreams {
+			if _, err := js.CreateOrUpdateStream(ctx, streamConfig(sc)); err != nil {
+				return fmt.Errorf("create stream %s: %w", sc.Name, err)
+			}
+			continue
+		}
+
+		stream, err := js.Stream(ctx, sc.Name)
+		if err != nil {
+			return fmt.Errorf("stream %s: %w", sc.Name, err)
+		}
+		info := stream.CachedInfo()
+		for _, subject := range sc.Subjects {
+			if !bindsSubject(info.Config.Subjects, subject) {
+				return fmt.Errorf("stream %s does not bind subject %s", sc.Name, subject)
+			}
+		}
+	}
+	return nil
+}
+
+func streamConfig(sc *conf.Nats_Stream) jetstream.StreamConfig {
+	cfg := jetstream.StreamConfig{
+		Name:     sc.Name,
+		Subjects: sc.Subjects,
+		Replicas: int(sc.Replicas),
+		MaxBytes: -1,
+		Storage:  jetstream.FileStorage,
+	}
+	if sc.MaxBytes > 0 {
+		cfg.MaxBytes = sc.MaxBytes
+	}
+	if sc.MaxAge != nil {
+		cfg.MaxAge = sc.MaxAge.AsDuration()
+	}
+	if sc.Duplicates != nil {
+		cfg.Duplicates = sc.Duplicates.AsDuration()
+	}
+	if strings.EqualFold(sc.Storage, "memory") {
+		cfg.Storage = jetstream.MemoryStorage
+	}
+	return cfg
+}
+
+// bindsSubject reports whether one of the stream subjects covers subject, stream
+// wildcards are honoured so orders.* binds orders.created.
+func bindsSubject(subjects []string, subject string) bool {
+	for _, s := range subjects {
+		if subjectCovers(s, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectCovers reports whether every subject matching subject also matches filter.
+func subjectCovers(filter, subject string) bool {
+	ft, st := strings.Split(filter, "."), strings.Split(subject, ".")
+	for i, f := range ft {
+		if f == ">" {
+			return len(st) > i
+		}
+		if i >= len(st) || st[i] == ">" {
+			return false
+		}
+		if f != "*" && (f != st[i] || st[i] == "*") {
+			return false
+		}
+	}
+	return len(ft) == len(st)
+}
+
+// JetStream returns the underlying JetStream context.
+func (p *JetStreamPublisher) JetStream() jetstream.JetStream {
+	return p.js
+}
+
+// Publish stores msg in the stream bound to topic and waits for the PubAck.
+// The Nats-Msg-Id header is used for de-duplication, when missing it is generated
+// and set on msg, so publishing the same msg again is de-duplicated.
+func (p *JetStreamPublisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	_, err := p.PublishWithAck(ctx, topic, msg)
+	return err
+}
+
+// PublishWithAck is Publish returning the PubAck, Duplicate is set when the message was already stored.
+func (p *JetStreamPublisher) PublishWithAck(ctx context.Context, topic string, msg *pubsub.Message) (*jetstream.PubAck, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ackTimeout)
+		defer cancel()
+	}
+	return p.js.PublishMsg(ctx, p.natsMsg(topic, msg))
+}
+
+// PublishFuture completes when the server acknowledged an async publish.
+type PublishFuture struct {
+	future jetstream.PubAckFuture
+}
+
+// Wait blocks until the message is acknowledged, rejected or ctx is done.
+func (f *PublishFuture) Wait(ctx context.Context) (*jetstream.PubAck, error) {
+	select {
+	case ack := <-f.future.Ok():
+		return ack, nil
+	case err := <-f.future.Err():
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// PublishAsync publishes without waiting for the PubAck. The number of outstanding
+// messages is bounded by MaxPending, once reached PublishAsync stalls and then fails.
+func (p *JetStreamPublisher) PublishAsync(topic string, msg *pubsub.Message) (*PublishFuture, error) {
+	future, err := p.js.PublishMsgAsync(p.natsMsg(topic, msg), jetstream.WithStallWait(p.ackTimeout))
+	if err != nil {
+		return nil, err
+	}
+	return &PublishFuture{future: future}, nil
+}
+
+func (p *JetStreamPublisher) natsMsg(topic string, msg *pubsub.Message) *nats.Msg {
+	// the id is kept on msg so retries of the same message share it
+	if msg.Header == nil {
+		msg.Header = pubsub.Header{}
+	}
+	if len(msg.Header.Get(MsgIDHeader)) == 0 {
+		msg.Header.Set(MsgIDHeader, nuid.Next())
+	}
+	return &nats.Msg{
+		Subject: topic,
+		Header:  toNatsHeader(msg.Header),
+		Data:    msg.Data,
+	}
+}
+
+// Close waits for outstanding async publishes and drains the connection when owned.
+func (p *JetStreamPublisher) Close() error {
+	var err error
+	select {
+	case <-p.js.PublishAsyncComplete():
+	case <-time.After(p.ackTimeout):
+		err = errors.New("nats: timeout waiting for pending publish acks")
+	}
+	if !p.owned {
+		return err
+	}
+	if drainErr := p.conn.Drain(); drainErr != nil {
+		return drainErr
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package nats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func TestSubjectCovers(t *testing.T) {
+	for _, tt := range []struct {
+		filter, subject string
+		covers          bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.*", true},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders.>", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.*", "orders.>", false},
+		{"orders.created", "orders.*", false},
+		{"orders.>", "orders", false},
+		{"orders.created", "payments.created", false},
+	} {
+		require.Equal(t, tt.covers, subjectCovers(tt.filter, tt.subject), "%s covers %s", tt.filter, tt.subject)
+	}
+}
+
+func TestJetStreamPublisher_RetryIsDeduplicated(t *testing.T) {
+	srv := runServer(t)
+	p, err := NewJetStreamPublisher(&conf.Nats{Addr: srv.ClientURL(), Jetstream: &conf.Nats_JetStream{
+		CreateStreams: true,
+		Streams:       []*conf.Nats_Stream{{Name: "ORDERS", Subjects: []string{"orders.*"}, Storage: "memory"}},
+	}})
+	require.Nil(t, err)
+	defer p.Close()
+
+	ctx := context.Background()
+	msg := &pubsub.Message{Data: []byte("1")}
+	ack, err := p.PublishWithAck(ctx, "orders.created", msg)
+	require.Nil(t, err)
+	require.False(t, ack.Duplicate)
+	require.NotEmpty(t, msg.Header.Get(MsgIDHeader))
+
+	ack, err = p.PublishWithAck(ctx, "orders.created", msg)
+	require.Nil(t, err)
+	require.True(t, ack.Duplicate)
+
+	future, err := p.PublishAsync("orders.created", msg)
+	require.Nil(t, err)
+	ack, err = future.Wait(ctx)
+	require.Nil(t, err)
+	require.True(t, ack.Duplicate)
+
+	ack, err = p.PublishWithAck(ctx, "orders.created", pubsub.NewMessage([]byte("1")))
+	require.Nil(t, err)
+	require.False(t, ack.Duplicate)
+	require.Equal(t, uint64(2), ack.Sequence)
+}
+
+func TestEnsureStreams_Wildcards(t *testing.T) {
+	srv := runServer(t)
+	conn, err := nats.Connect(srv.ClientURL())
+	require.Nil(t, err)
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	require.Nil(t, err)
+	ctx := context.Background()
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.*"}, Storage: jetstream.MemoryStorage})
+	require.Nil(t, err)
+
+	require.Nil(t, EnsureStreams(ctx, js, &conf.Nats_JetStream{
+		Streams: []*conf.Nats_Stream{{Name: "ORDERS", Subjects: []string{"orders.created", "orders.*"}}},
+	}))
+	require.NotNil(t, EnsureStreams(ctx, js, &conf.Nats_JetStream{
+		Streams: []*conf.Nats_Stream{{Name: "ORDERS", Subjects: []string{"orders.created.eu"}}},
+	}))
+}
@@ -0,0 +1,74 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/wire"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// ProviderSet is nats publisher providers, the connection is drained on app cleanup.
+var ProviderSet = wire.NewSet(ProvidePublisher, wire.Bind(new(pubsub.Publisher), new(*Publisher)))
+
+var _ pubsub.Publisher = (*Publisher)(nil)
+
+// Publisher publishes on core NATS.
+type Publisher struct {
+	conn  *nats.Conn
+	owned bool
+}
+
+// NewPublisher connects to NATS, the connection is drained by Close.
+func NewPublisher(c *conf.Nats) (*Publisher, error) {
+	conn, err := Connect(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Publisher{conn: conn, owned: true}, nil
+}
+
+// ProvidePublisher is the wire provider of NewPublisher, the cleanup closes the publisher.
+func ProvidePublisher(c *conf.Nats, logger log.Logger) (*Publisher, func(), error) {
+	p, err := NewPublisher(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, func() {
+		if err := p.Close(); err != nil {
+			log.NewHelper(logger).Errorw("msg", "nats publisher not closed", "error", err)
+		}
+	}, nil
+}
+
+// NewPublisherWithConn publishes on a connection owned by the caller, Close leaves it open.
+func NewPublisherWithConn(conn *nats.Conn) *Publisher {
+	return &Publisher{conn: conn}
+}
+
+// Conn returns the underlying connection.
+func (p *Publisher) Conn() *nats.Conn {
+	return p.conn
+}
+
+func (p *Publisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.conn.PublishMsg(&nats.Msg{
+		Subject: topic,
+		Header:  toNatsHeader(msg.Header),
+		Data:    msg.Data,
+	})
+}
+
+// Close drains pending messages and closes the connection when the publisher owns it.
+func (p *Publisher) Close() error {
+	if !p.owned {
+		return p.conn.Flush()
+	}
+	return p.conn.Drain()
+}
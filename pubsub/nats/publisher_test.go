@@ -0,0 +1,46 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// runServer starts an embedded NATS server with JetStream, stopped when the test ends.
+func runServer(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func TestProvidePublisher_CleanupDrains(t *testing.T) {
+	s := runServer(t)
+	p, cleanup, err := ProvidePublisher(&conf.Nats{Addr: s.ClientURL()}, log.DefaultLogger)
+	require.Nil(t, err)
+
+	sub, err := nats.Connect(s.ClientURL())
+	require.Nil(t, err)
+	defer sub.Close()
+	ch := make(chan *nats.Msg, 1)
+	_, err = sub.ChanSubscribe("orders.created", ch)
+	require.Nil(t, err)
+	require.Nil(t, sub.Flush())
+
+	require.Nil(t, p.Publish(context.Background(), "orders.created", pubsub.NewMessage([]byte("1"))))
+	cleanup()
+	require.Equal(t, "1", string((<-ch).Data))
+	require.Eventually(t, p.Conn().IsClosed, time.Second, time.Millisecond)
+}
@@ -0,0 +1,272 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+// Headers of request/reply messages.
+const (
+	// DeadlineHeader carries the deadline of the caller context, in RFC 3339 format.
+	DeadlineHeader = "Request-Deadline"
+
+	ErrorTypeHeader    = "Error-Type"
+	ErrorCodeHeader    = "Error-Code"
+	ErrorReasonHeader  = "Error-Reason"
+	ErrorMessageHeader = "Error-Message"
+
+	errorTypeStatus = "status"
+	errorTypeKratos = "kratos"
+)
+
+const defaultRequestTimeout = 5 * time.Second
+
+// Client sends requests over NATS request/reply.
+type Client struct {
+	conn    *nats.Conn
+	owned   bool
+	codecs  *pubsub.Codecs
+	timeout time.Duration
+}
+
+// ClientOption is request client option.
+type ClientOption func(*Client)
+
+// WithCodecs selects the request codec per subject, default is pubsub.DefaultCodecs.
+func WithCodecs(codecs *pubsub.Codecs) ClientOption {
+	return func(c *Client) {
+		c.codecs = codecs
+	}
+}
+
+// WithRequestTimeout is used when the request context has no deadline.
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient connects to NATS, the connection is drained by Close.
+func NewClient(c *conf.Nats, opts ...ClientOption) (*Client, error) {
+	conn, err := Connect(c)
+	if err != nil {
+		return nil, err
+	}
+	client := NewClientWithConn(conn, opts...)
+	client.owned = true
+	return client, nil
+}
+
+// NewClientWithConn sends requests on a connection owned by the caller.
+func NewClientWithConn(conn *nats.Conn, opts ...ClientOption) *Client {
+	c := &Client{
+		conn:    conn,
+		codecs:  pubsub.DefaultCodecs,
+		timeout: defaultRequestTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Close drains the connection when the client owns it.
+func (c *Client) Close() error {
+	if !c.owned {
+		return nil
+	}
+	return c.conn.Drain()
+}
+
+// Request sends req on subject and decodes the reply into Resp. The context deadline,
+// or the client timeout, bounds the request and is propagated to the responder.
+// An error returned by the responder is returned as *uerror.StatusError or *errors.Error.
+func Request[Req, Resp any](ctx context.Context, c *Client, subject string, req Req) (Resp, error) {
+	var resp Resp
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	msg, err := c.newMsg(ctx, subject, req)
+	if err != nil {
+		return resp, err
+	}
+	reply, err := c.conn.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		return resp, err
+	}
+	return decodeReply[Resp](reply)
+}
+
+// Reply is one of the replies collected by Gather.
+type Reply[Resp any] struct {
+	Value Resp
+	Err   error
+}
+
+// Gather sends req on subject and collects the replies of every responder until n replies
+// arrived or the context deadline, or the client timeout, expired. n <= 0 collects until
+// the deadline. Replies gathered so far are returned with nats.ErrTimeout when fewer than n arrived.
+func Gather[Req, Resp any](ctx context.Context, c *Client, subject string, req Req, n int) ([]Reply[Resp], error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	msg, err := c.newMsg(ctx, subject, req)
+	if err != nil {
+		return nil, err
+	}
+	inbox := c.conn.NewInbox()
+	sub, err := c.conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = sub.Unsubscribe()
+	}()
+	msg.Reply = inbox
+	if err := c.conn.PublishMsg(msg); err != nil {
+		return nil, err
+	}
+
+	var replies []Reply[Resp]
+	for n <= 0 || len(replies) < n {
+		m, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if n <= 0 && ctx.Err() != nil {
+				return replies, nil
+			}
+			if ctx.Err() != nil {
+				return replies, nats.ErrTimeout
+			}
+			return replies, err
+		}
+		value, err := decodeReply[Resp](m)
+		replies = append(replies, Reply[Resp]{Value: value, Err: err})
+	}
+	return replies, nil
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *Client) newMsg(ctx context.Context, subject string, req interface{}) (*nats.Msg, error) {
+	m, err := c.codecs.Encode(subject, req)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		m.Header.Set(DeadlineHeader, deadline.UTC().Format(time.RFC3339Nano))
+	}
+	return &nats.Msg{Subject: subject, Header: toNatsHeader(m.Header), Data: m.Data}, nil
+}
+
+func decodeReply[Resp any](m *nats.Msg) (Resp, error) {
+	msg := fromNatsMsg(m)
+	if err := decodeError(msg.Header); err != nil {
+		var resp Resp
+		return resp, err
+	}
+	return pubsub.DecodeValue[Resp](msg)
+}
+
+// Router registers message handlers on NATS subjects, it is implemented by *Subscriber
+// and *Server.
+type Router interface {
+	Subscribe(subject, queueGroup string, handler pubsub.Handler) error
+}
+
+var (
+	_ Router = (*Subscriber)(nil)
+	_ Router = (*Server)(nil)
+)
+
+// Respond registers a typed responder on subject. Requests are decoded with the codec of
+// their content type and replies are encoded with the same codec. The caller deadline is
+// set on the handler context and a returned error is sent back to the caller, errors that
+// are not *errors.Error or *uerror.StatusError are sent as a generic internal error.
+func Respond[Req, Resp any](s Router, subject, queueGroup string, handler func(ctx context.Context, req Req) (Resp, error)) error {
+	return s.Subscribe(subject, queueGroup, func(ctx context.Context, msg *pubsub.Message) error {
+		m, ok := natsMsgFromContext(ctx)
+		if !ok || len(m.Reply) == 0 {
+			return uerror.BadRequestError("nats: request without reply subject")
+		}
+		if deadline, err := time.Parse(time.RFC3339Nano, msg.Header.Get(DeadlineHeader)); err == nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		reply, err := respond(ctx, msg, handler)
+		if err != nil {
+			reply = pubsub.NewMessage(nil)
+			encodeError(reply.Header, err)
+		}
+		if respErr := m.RespondMsg(&nats.Msg{Header: toNatsHeader(reply.Header), Data: reply.Data}); respErr != nil {
+			return respErr
+		}
+		return err
+	})
+}
+
+func respond[Req, Resp any](ctx context.Context, msg *pubsub.Message, handler func(ctx context.Context, req Req) (Resp, error)) (*pubsub.Message, error) {
+	req, err := pubsub.DecodeValue[Req](msg)
+	if err != nil {
+		return nil, uerror.BadRequestError(err.Error())
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	codec := pubsub.CodecForContentType(msg.Header.Get(pubsub.ContentTypeHeader))
+	if codec == nil {
+		return pubsub.Encode("json", resp)
+	}
+	return pubsub.Encode(codec.Name(), resp)
+}
+
+// errInternal is sent instead of untyped errors, their message may leak internals.
+var errInternal = kerrors.InternalServer(uerror.INTERNAL_SERVER_ERROR, "internal error")
+
+func encodeError(h pubsub.Header, err error) {
+	var se *uerror.StatusError
+	if errors.As(err, &se) {
+		h.Set(ErrorTypeHeader, errorTypeStatus)
+		h.Set(ErrorCodeHeader, strconv.FormatInt(se.Code, 10))
+		h.Set(ErrorReasonHeader, se.Key)
+		h.Set(ErrorMessageHeader, se.Message)
+		return
+	}
+	var ke *kerrors.Error
+	if !errors.As(err, &ke) {
+		ke = errInternal
+	}
+	h.Set(ErrorTypeHeader, errorTypeKratos)
+	h.Set(ErrorCodeHeader, strconv.FormatInt(int64(ke.Code), 10))
+	h.Set(ErrorReasonHeader, ke.Reason)
+	h.Set(ErrorMessageHeader, ke.Message)
+}
+
+func decodeError(h pubsub.Header) error {
+	errType := h.Get(ErrorTypeHeader)
+	if len(errType) == 0 {
+		return nil
+	}
+	code, _ := strconv.ParseInt(h.Get(ErrorCodeHeader), 10, 64)
+	reason, message := h.Get(ErrorReasonHeader), h.Get(ErrorMessageHeader)
+	if errType == errorTypeStatus {
+		return &uerror.StatusError{Code: code, Key: reason, Message: message, Err: errors.New(message)}
+	}
+	return kerrors.New(int(code), reason, message)
+}
@@ -0,0 +1,126 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+func TestRequest_ErrorMapping(t *testing.T) {
+	h := pubsub.Header{}
+	encodeError(h, uerror.NotFoundError("order not found"))
+	err := decodeError(h)
+	var se *uerror.StatusError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, int64(http.StatusNotFound), se.Code)
+	require.Equal(t, uerror.NOT_FOUND, se.Key)
+	require.Equal(t, "order not found", se.Error())
+
+	h = pubsub.Header{}
+	encodeError(h, kerrors.Conflict("ORDER_EXISTS", "order exists"))
+	err = decodeError(h)
+	require.True(t, kerrors.IsConflict(err))
+	require.Equal(t, "ORDER_EXISTS", kerrors.Reason(err))
+
+	h = pubsub.Header{}
+	encodeError(h, errors.New("dial tcp 10.0.0.7:5432: connection refused"))
+	err = decodeError(h)
+	require.Equal(t, http.StatusInternalServerError, kerrors.Code(err))
+	require.Equal(t, uerror.INTERNAL_SERVER_ERROR, kerrors.Reason(err))
+	require.NotContains(t, err.Error(), "10.0.0.7")
+
+	require.Nil(t, decodeError(pubsub.Header{}))
+}
+
+func newClient(t *testing.T, url string, opts ...ClientOption) *Client {
+	conn, err := nats.Connect(url)
+	require.Nil(t, err)
+	c := NewClientWithConn(conn, opts...)
+	c.owned = true
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestRequest(t *testing.T) {
+	srv := runServer(t)
+	s := newSubscriber(t, srv.ClientURL())
+	defer s.Close()
+	require.Nil(t, Respond(s, "orders.get", "", func(ctx context.Context, req order) (order, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return order{}, errors.New("no deadline")
+		}
+		if req.ID == 0 {
+			return order{}, kerrors.NotFound("ORDER_NOT_FOUND", "order not found")
+		}
+		return order{ID: req.ID * 10}, nil
+	}))
+	require.Nil(t, s.conn.Flush())
+	c := newClient(t, srv.ClientURL())
+
+	resp, err := Request[order, order](context.Background(), c, "orders.get", order{ID: 4})
+	require.Nil(t, err)
+	require.Equal(t, 40, resp.ID)
+
+	_, err = Request[order, order](context.Background(), c, "orders.get", order{})
+	require.True(t, kerrors.IsNotFound(err))
+	require.Equal(t, "ORDER_NOT_FOUND", kerrors.Reason(err))
+
+	_, err = Request[order, order](context.Background(), c, "orders.unknown", order{ID: 1})
+	require.ErrorIs(t, err, nats.ErrNoResponders)
+}
+
+func TestGather(t *testing.T) {
+	srv := runServer(t)
+	for i := 1; i <= 2; i++ {
+		i := i
+		s := newSubscriber(t, srv.ClientURL())
+		defer s.Close()
+		require.Nil(t, Respond(s, "stock.count", "", func(ctx context.Context, req order) (order, error) {
+			return order{ID: i}, nil
+		}))
+		require.Nil(t, s.conn.Flush())
+	}
+	c := newClient(t, srv.ClientURL(), WithRequestTimeout(100*time.Millisecond))
+
+	replies, err := Gather[order, order](context.Background(), c, "stock.count", order{}, 2)
+	require.Nil(t, err)
+	require.Len(t, replies, 2)
+	require.ElementsMatch(t, []int{1, 2}, []int{replies[0].Value.ID, replies[1].Value.ID})
+
+	replies, err = Gather[order, order](context.Background(), c, "stock.count", order{}, 0)
+	require.Nil(t, err)
+	require.Len(t, replies, 2)
+
+	replies, err = Gather[order, order](context.Background(), c, "stock.count", order{}, 3)
+	require.ErrorIs(t, err, nats.ErrTimeout)
+	require.Len(t, replies, 2)
+}
+
+func TestRespond_Server(t *testing.T) {
+	natsSrv := runServer(t)
+	srv := NewServer(&conf.Nats{Addr: natsSrv.ClientURL()})
+	require.Nil(t, Respond(srv, "orders.get", "", func(ctx context.Context, req order) (order, error) {
+		if _, ok := transport.FromServerContext(ctx); !ok {
+			return order{}, errors.New("no server transport")
+		}
+		return order{ID: req.ID * 10}, nil
+	}))
+	require.Nil(t, srv.Start(context.Background()))
+	defer func() { _ = srv.Stop(context.Background()) }()
+	require.Nil(t, srv.subscriber.conn.Flush())
+
+	resp, err := Request[order, order](context.Background(), newClient(t, natsSrv.ClientURL()), "orders.get", order{ID: 4})
+	require.Nil(t, err)
+	require.Equal(t, 40, resp.ID)
+}
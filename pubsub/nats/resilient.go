@@ -0,0 +1,324 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/metrics"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+var (
+	// ErrBufferFull is returned when the publish buffer is full, the message is dropped.
+	ErrBufferFull = errors.New("nats: publish buffer full")
+	// ErrPublisherClosed is returned when publishing on a closed publisher.
+	ErrPublisherClosed = errors.New("nats: publisher closed")
+)
+
+const (
+	defaultBufferSize     = 8192
+	defaultBatchSize      = 64
+	defaultReconnectDelay = 100 * time.Millisecond
+	defaultReconnectMax   = 30 * time.Second
+	defaultFlushTimeout   = 5 * time.Second
+)
+
+var _ pubsub.Publisher = (*ResilientPublisher)(nil)
+
+// ResilientOption is resilient publisher option.
+type ResilientOption func(*ResilientPublisher)
+
+// WithBufferSize bounds the number of messages waiting to be sent.
+func WithBufferSize(n int) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.bufferSize = n
+	}
+}
+
+// WithBatch sends up to size buffered messages per flush and waits up to linger for a
+// batch to fill, a zero linger sends whatever is buffered right away.
+func WithBatch(size int, linger time.Duration) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.batchSize = size
+		p.linger = linger
+	}
+}
+
+// WithFlushTimeout bounds the wait for the server to confirm a batch was received.
+func WithFlushTimeout(timeout time.Duration) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.flushTimeout = timeout
+	}
+}
+
+// WithReconnectBackoff sets the reconnect delay, it doubles per attempt up to max.
+func WithReconnectBackoff(initial, max time.Duration) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.backoffInitial = initial
+		p.backoffMax = max
+	}
+}
+
+// WithBufferGauge with a gauge set to the number of buffered messages.
+func WithBufferGauge(g metrics.Gauge) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.bufferGauge = g
+	}
+}
+
+// WithDrops with a counter of messages dropped because the buffer was full, labels are {topic}.
+func WithDrops(c metrics.Counter) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.drops = c
+	}
+}
+
+// WithPublisherLogger with publisher logger.
+func WithPublisherLogger(logger log.Logger) ResilientOption {
+	return func(p *ResilientPublisher) {
+		p.log = log.NewHelper(logger)
+	}
+}
+
+// ResilientPublisher buffers messages in memory and sends them from a single writer,
+// so publishing never blocks on the network. While NATS is unreachable messages are
+// kept up to the buffer size and the connection is retried with exponential backoff,
+// once the buffer is full Publish drops the message and returns ErrBufferFull.
+// A nil error from Publish means the message was buffered, not delivered.
+// Each batch is written and flushed, a batch whose write or flush failed is sent
+// again after the reconnect, so a message may be delivered twice; set the
+// Nats-Msg-Id header to let JetStream drop the duplicates.
+type ResilientPublisher struct {
+	conn           *nats.Conn
+	log            *log.Helper
+	bufferSize     int
+	batchSize      int
+	linger         time.Duration
+	flushTimeout   time.Duration
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	bufferGauge    metrics.Gauge
+	drops          metrics.Counter
+
+	mu          sync.RWMutex
+	closed      bool
+	queue       chan *nats.Msg
+	reconnected chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewResilientPublisher connects to NATS in the background, the first connection
+// may fail and is retried like a reconnect.
+func NewResilientPublisher(c *conf.Nats, opts ...ResilientOption) (*ResilientPublisher, error) {
+	p := &ResilientPublisher{
+		log:            log.NewHelper(log.GetLogger()),
+		bufferSize:     defaultBufferSize,
+		batchSize:      defaultBatchSize,
+		flushTimeout:   defaultFlushTimeout,
+		backoffInitial: defaultReconnectDelay,
+		backoffMax:     defaultReconnectMax,
+		reconnected:    make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.queue = make(chan *nats.Msg, p.bufferSize)
+
+	maxReconnects := -1
+	if c.MaxReconnects > 0 {
+		maxReconnects = int(c.MaxReconnects)
+	}
+	conn, err := Connect(c,
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(maxReconnects),
+		nats.CustomReconnectDelay(p.reconnectDelay),
+		// messages are buffered by the publisher, not by the client
+		nats.ReconnectBufSize(-1),
+		nats.ConnectHandler(p.onConnect),
+		nats.ReconnectHandler(p.onConnect),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				p.log.Warnw("msg", "nats disconnected", "error", err)
+			}
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.conn = conn
+	go p.run()
+	return p, nil
+}
+
+// reconnectDelay doubles the delay per attempt with a 20% jitter.
+func (p *ResilientPublisher) reconnectDelay(attempts int) time.Duration {
+	delay := p.backoffInitial
+	for i := 1; i < attempts && delay < p.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > p.backoffMax {
+		delay = p.backoffMax
+	}
+	if jitter := int64(delay) / 5; jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	return delay
+}
+
+func (p *ResilientPublisher) onConnect(*nats.Conn) {
+	select {
+	case p.reconnected <- struct{}{}:
+	default:
+	}
+}
+
+// Publish buffers msg, it returns ErrBufferFull when the buffer is full.
+func (p *ResilientPublisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPublisherClosed
+	}
+	select {
+	case p.queue <- &nats.Msg{Subject: topic, Header: toNatsHeader(msg.Header), Data: msg.Data}:
+		p.reportBuffer()
+		return nil
+	default:
+		if p.drops != nil {
+			p.drops.With(topic).Inc()
+		}
+		return ErrBufferFull
+	}
+}
+
+// Buffered returns the number of messages waiting to be sent.
+func (p *ResilientPublisher) Buffered() int {
+	return len(p.queue)
+}
+
+func (p *ResilientPublisher) reportBuffer() {
+	if p.bufferGauge != nil {
+		p.bufferGauge.Set(float64(len(p.queue)))
+	}
+}
+
+// run is the single writer, it sends the buffered messages in batches.
+func (p *ResilientPublisher) run() {
+	defer close(p.done)
+	batch := make([]*nats.Msg, 0, p.batchSize)
+	for {
+		select {
+		case m := <-p.queue:
+			batch = append(batch[:0], m)
+		case <-p.stop:
+			return
+		}
+		batch = p.fill(batch)
+		if !p.send(batch) {
+			return
+		}
+		p.reportBuffer()
+	}
+}
+
+// fill adds buffered messages to batch up to the batch size, waiting at most linger.
+func (p *ResilientPublisher) fill(batch []*nats.Msg) []*nats.Msg {
+	var linger <-chan time.Time
+	if p.linger > 0 {
+		timer := time.NewTimer(p.linger)
+		defer timer.Stop()
+		linger = timer.C
+	}
+	for len(batch) < p.batchSize {
+		select {
+		case m := <-p.queue:
+			batch = append(batch, m)
+			continue
+		default:
+		}
+		if linger == nil {
+			return batch
+		}
+		select {
+		case m := <-p.queue:
+			batch = append(batch, m)
+		case <-linger:
+			return batch
+		case <-p.stop:
+			return batch
+		}
+	}
+	return batch
+}
+
+// send publishes batch in order, waiting for the connection while NATS is unreachable.
+// It returns false when the publisher stopped before the batch was sent.
+func (p *ResilientPublisher) send(batch []*nats.Msg) bool {
+	for {
+		err := p.write(batch)
+		if err == nil {
+			return true
+		}
+		if p.conn.IsClosed() {
+			p.log.Errorw("msg", "nats connection closed, dropping buffered messages", "error", err)
+			return false
+		}
+		select {
+		case <-p.reconnected:
+		case <-time.After(p.backoffInitial):
+		case <-p.stop:
+			return false
+		}
+	}
+}
+
+// write publishes batch and flushes it. Messages written but not flushed are lost
+// on a disconnect, so a failed batch is sent again as a whole.
+func (p *ResilientPublisher) write(batch []*nats.Msg) error {
+	for _, m := range batch {
+		if err := p.conn.PublishMsg(m); err != nil {
+			return err
+		}
+	}
+	return p.conn.FlushTimeout(p.flushTimeout)
+}
+
+// Close stops accepting messages and, while connected, waits up to the drain timeout
+// for the buffer to be sent, then drains the connection. Messages still buffered are dropped.
+func (p *ResilientPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	deadline := time.Now().Add(p.conn.Opts.DrainTimeout)
+	for len(p.queue) > 0 && time.Now().Before(deadline) && p.conn.IsConnected() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(p.stop)
+	<-p.done
+	if n := len(p.queue); n > 0 {
+		p.log.Errorw("msg", "nats publisher closed with buffered messages", "dropped", n)
+	}
+	if !p.conn.IsConnected() {
+		p.conn.Close()
+		return nil
+	}
+	return p.conn.Drain()
+}
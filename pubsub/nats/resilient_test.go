@@ -0,0 +1,122 @@
+package nats
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/metrics"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+type counter struct {
+	labels []string
+	n      int
+}
+
+func (c *counter) With(labels ...string) metrics.Counter {
+	c.labels = labels
+	return c
+}
+
+func (c *counter) Inc() { c.n++ }
+
+func (c *counter) Add(delta float64) { c.n += int(delta) }
+
+func TestResilientPublisher_BuffersWhileOffline(t *testing.T) {
+	drops := &counter{}
+	p, err := NewResilientPublisher(&conf.Nats{Addr: "nats://127.0.0.1:1"},
+		WithBufferSize(2),
+		WithBatch(1, 0),
+		WithReconnectBackoff(time.Hour, time.Hour),
+		WithDrops(drops),
+	)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	require.Nil(t, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte("1"))))
+	require.Eventually(t, func() bool {
+		// the writer holds the message while it waits for the connection
+		return p.Buffered() == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Nil(t, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte("2"))))
+	require.Nil(t, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte("3"))))
+	require.Equal(t, ErrBufferFull, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte("4"))))
+	require.Equal(t, 1, drops.n)
+	require.Equal(t, []string{"orders.created"}, drops.labels)
+
+	require.Nil(t, p.Close())
+	require.Equal(t, ErrPublisherClosed, p.Publish(ctx, "orders.created", pubsub.NewMessage(nil)))
+}
+
+func TestResilientPublisher_ReconnectDelay(t *testing.T) {
+	p := &ResilientPublisher{backoffInitial: 100 * time.Millisecond, backoffMax: time.Second}
+	require.InDelta(t, 100*time.Millisecond, p.reconnectDelay(1), float64(20*time.Millisecond))
+	require.InDelta(t, 400*time.Millisecond, p.reconnectDelay(3), float64(80*time.Millisecond))
+	require.InDelta(t, time.Second, p.reconnectDelay(10), float64(200*time.Millisecond))
+}
+
+func TestResilientPublisher_ResendsAfterReconnect(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	srv := natstest.RunServer(&opts)
+	opts.Port = srv.Addr().(*net.TCPAddr).Port
+
+	p, err := NewResilientPublisher(&conf.Nats{Addr: srv.ClientURL()},
+		WithBatch(4, 10*time.Millisecond),
+		WithFlushTimeout(100*time.Millisecond),
+		WithReconnectBackoff(200*time.Millisecond, 200*time.Millisecond),
+	)
+	require.Nil(t, err)
+	defer p.Close()
+
+	receive := func(url string) chan *nats.Msg {
+		conn, err := nats.Connect(url)
+		require.Nil(t, err)
+		t.Cleanup(conn.Close)
+		ch := make(chan *nats.Msg, 16)
+		_, err = conn.ChanSubscribe("orders.created", ch)
+		require.Nil(t, err)
+		require.Nil(t, conn.Flush())
+		return ch
+	}
+	// a resent batch may deliver a message twice
+	expect := func(ch chan *nats.Msg, data ...string) {
+		missing := map[string]bool{}
+		for _, d := range data {
+			missing[d] = true
+		}
+		for len(missing) > 0 {
+			select {
+			case m := <-ch:
+				delete(missing, string(m.Data))
+			case <-time.After(2 * time.Second):
+				t.Fatalf("messages %v not received", missing)
+			}
+		}
+	}
+
+	ctx := context.Background()
+	ch := receive(srv.ClientURL())
+	for _, d := range []string{"1", "2", "3"} {
+		require.Nil(t, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte(d))))
+	}
+	expect(ch, "1", "2", "3")
+
+	srv.Shutdown()
+	require.Eventually(t, func() bool { return !p.conn.IsConnected() }, time.Second, 5*time.Millisecond)
+	for _, d := range []string{"4", "5", "6"} {
+		require.Nil(t, p.Publish(ctx, "orders.created", pubsub.NewMessage([]byte(d))))
+	}
+
+	srv = natstest.RunServer(&opts)
+	defer srv.Shutdown()
+	// subscribed before the publisher reconnects after its 200ms backoff
+	expect(receive(srv.ClientURL()), "4", "5", "6")
+}
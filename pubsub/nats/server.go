@@ -0,0 +1,184 @@
+package nats
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+var (
+	_ transport.Server     = (*Server)(nil)
+	_ transport.Endpointer = (*Server)(nil)
+)
+
+// ServerOption is NATS server option.
+type ServerOption func(*Server)
+
+// Middleware with server middleware, it runs around every message handler.
+func Middleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = m
+	}
+}
+
+// Logger with server logger.
+func Logger(logger log.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// QueueGroup sets the queue group used by Handle when none is given.
+func QueueGroup(queueGroup string) ServerOption {
+	return func(s *Server) {
+		s.queueGroup = queueGroup
+	}
+}
+
+// Concurrency bounds the number of running handlers.
+func Concurrency(n int) ServerOption {
+	return func(s *Server) {
+		s.subOpts = append(s.subOpts, WithConcurrency(n))
+	}
+}
+
+// Timeout with handler timeout.
+func Timeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.subOpts = append(s.subOpts, WithHandlerTimeout(timeout))
+	}
+}
+
+type route struct {
+	subject    string
+	queueGroup string
+	handler    pubsub.Handler
+}
+
+// Server is a kratos transport.Server consuming NATS subjects, so message
+// handlers share the app lifecycle and the middleware chain of HTTP and gRPC.
+type Server struct {
+	conf       *conf.Nats
+	logger     log.Logger
+	queueGroup string
+	middleware []middleware.Middleware
+	subOpts    []SubscriberOption
+
+	mu         sync.Mutex
+	routes     []route
+	subscriber *Subscriber
+}
+
+func NewServer(c *conf.Nats, opts ...ServerOption) *Server {
+	s := &Server{
+		conf:       c,
+		logger:     log.GetLogger(),
+		queueGroup: c.QueueGroup,
+	}
+	if c.Concurrency > 0 {
+		s.subOpts = append(s.subOpts, WithConcurrency(int(c.Concurrency)))
+	}
+	if c.HandlerTimeout != nil {
+		s.subOpts = append(s.subOpts, WithHandlerTimeout(c.HandlerTimeout.AsDuration()))
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handle registers handler on subject, an empty queueGroup falls back to the server queue group.
+// Handlers must be registered before the server starts.
+func (s *Server) Handle(subject, queueGroup string, handler pubsub.Handler) {
+	if len(queueGroup) == 0 {
+		queueGroup = s.queueGroup
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, route{subject: subject, queueGroup: queueGroup, handler: handler})
+}
+
+// Subscribe implements pubsub.Subscriber so typed handlers can be registered with pubsub.Subscribe.
+func (s *Server) Subscribe(subject, queueGroup string, handler pubsub.Handler) error {
+	s.Handle(subject, queueGroup, handler)
+	return nil
+}
+
+// Close implements pubsub.Subscriber, it is the same as Stop.
+func (s *Server) Close() error {
+	return s.Stop(context.Background())
+}
+
+func (s *Server) Endpoint() (*url.URL, error) {
+	addr := s.conf.Addr
+	if len(addr) == 0 {
+		addr = nats.DefaultURL
+	}
+	return url.Parse(addr)
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	conn, err := Connect(s.conf)
+	if err != nil {
+		return err
+	}
+	subscriber := NewSubscriberWithConn(conn, s.logger, s.subOpts...)
+	subscriber.owned = true
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriber = subscriber
+	for _, r := range s.routes {
+		if err := subscriber.Subscribe(r.subject, r.queueGroup, s.wrap(conn.ConnectedUrl(), r)); err != nil {
+			return err
+		}
+	}
+	log.NewHelper(s.logger).Infof("[NATS] server listening on: %s", conn.ConnectedUrl())
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	subscriber := s.subscriber
+	s.subscriber = nil
+	s.mu.Unlock()
+	if subscriber == nil {
+		return nil
+	}
+	log.NewHelper(s.logger).Info("[NATS] server stopping")
+	return subscriber.Close()
+}
+
+// wrap runs the middleware chain around handler with a NATS transport in the context.
+func (s *Server) wrap(endpoint string, r route) pubsub.Handler {
+	return wrapHandler(endpoint, r.queueGroup, s.middleware, r.handler)
+}
+
+func wrapHandler(endpoint, queueGroup string, ms []middleware.Middleware, handler pubsub.Handler) pubsub.Handler {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, handler(ctx, req.(*pubsub.Message))
+	}
+	if len(ms) > 0 {
+		next = middleware.Chain(ms...)(next)
+	}
+	return func(ctx context.Context, msg *pubsub.Message) error {
+		tr := &Transport{
+			endpoint:    endpoint,
+			operation:   msg.Subject,
+			queueGroup:  queueGroup,
+			reqHeader:   msg.Header,
+			replyHeader: pubsub.Header{},
+		}
+		_, err := next(transport.NewServerContext(ctx, tr), msg)
+		return err
+	}
+}
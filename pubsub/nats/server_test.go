@@ -0,0 +1,73 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func TestServer_WrapMiddleware(t *testing.T) {
+	var operations []string
+	mw := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			require.True(t, ok)
+			require.Equal(t, KindNATS, tr.Kind())
+			require.Equal(t, "Bearer token", tr.RequestHeader().Get("Authorization"))
+			operations = append(operations, tr.Operation())
+			return handler(ctx, req)
+		}
+	}
+	srv := NewServer(&conf.Nats{QueueGroup: "workers"}, Middleware(mw))
+
+	var received string
+	handler := srv.wrap("nats://127.0.0.1:4222", route{subject: "order.*", queueGroup: "workers", handler: func(ctx context.Context, msg *pubsub.Message) error {
+		received = string(msg.Data)
+		return nil
+	}})
+
+	msg := &pubsub.Message{Subject: "order.created", Header: pubsub.Header{}, Data: []byte("hello")}
+	msg.Header.Set("Authorization", "Bearer token")
+	require.Nil(t, handler(context.Background(), msg))
+	require.Equal(t, "hello", received)
+	require.Equal(t, []string{"order.created"}, operations)
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	natsSrv := runServer(t)
+	srv := NewServer(&conf.Nats{Addr: natsSrv.ClientURL()}, QueueGroup("workers"))
+
+	received := make(chan string, 2)
+	srv.Handle("order.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		tr, ok := transport.FromServerContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, "order.created", tr.Operation())
+		received <- string(msg.Data)
+		return nil
+	})
+	require.Nil(t, srv.Start(context.Background()))
+
+	publish(t, natsSrv.ClientURL(), "order.created", []byte("first"))
+	select {
+	case data := <-received:
+		require.Equal(t, "first", data)
+	case <-time.After(time.Second):
+		t.Fatal("message not handled")
+	}
+
+	require.Nil(t, srv.Stop(context.Background()))
+	require.Nil(t, srv.Stop(context.Background()))
+	publish(t, natsSrv.ClientURL(), "order.created", []byte("second"))
+	select {
+	case data := <-received:
+		t.Fatalf("handled %q after stop", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,222 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+var _ pubsub.Subscriber = (*Subscriber)(nil)
+
+// SubscriberOption is subscriber option.
+type SubscriberOption func(*Subscriber)
+
+// WithConcurrency bounds the number of handlers running at the same time, 1 keeps messages in order.
+func WithConcurrency(n int) SubscriberOption {
+	return func(s *Subscriber) {
+		if n > 0 {
+			s.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithHandlerTimeout sets a deadline on the context passed to handlers.
+func WithHandlerTimeout(timeout time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.timeout = timeout
+	}
+}
+
+// Subscriber runs handlers for messages received on core NATS.
+type Subscriber struct {
+	conn    *nats.Conn
+	owned   bool
+	log     *log.Helper
+	sem     chan struct{}
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	subs   []*nats.Subscription
+	closed bool
+}
+
+// NewSubscriber connects to NATS, the connection is drained by Close.
+func NewSubscriber(c *conf.Nats, logger log.Logger, opts ...SubscriberOption) (*Subscriber, error) {
+	conn, err := Connect(c)
+	if err != nil {
+		return nil, err
+	}
+	s := NewSubscriberWithConn(conn, logger, opts...)
+	s.owned = true
+	return s, nil
+}
+
+// NewSubscriberWithConn subscribes on a connection owned by the caller.
+func NewSubscriberWithConn(conn *nats.Conn, logger log.Logger, opts ...SubscriberOption) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Subscriber{
+		conn:   conn,
+		log:    log.NewHelper(logger),
+		sem:    make(chan struct{}, 64),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Subscriber) Subscribe(subject, queueGroup string, handler pubsub.Handler) error {
+	sub, err := s.conn.QueueSubscribe(subject, queueGroup, func(m *nats.Msg) {
+		s.dispatch(handler, m)
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.subs = append(s.subs, sub)
+	s.mu.Unlock()
+	return nil
+}
+
+// Unsubscribe drains the subscriptions of subject and queueGroup, their pending
+// messages are still dispatched.
+func (s *Subscriber) Unsubscribe(subject, queueGroup string) error {
+	s.mu.Lock()
+	var matched []*nats.Subscription
+	subs := s.subs[:0]
+	for _, sub := range s.subs {
+		if sub.Subject == subject && sub.Queue == queueGroup {
+			matched = append(matched, sub)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	s.subs = subs
+	s.mu.Unlock()
+	return s.drain(matched)
+}
+
+// dispatch runs handler in its own goroutine, it blocks the subscription while
+// the concurrency limit is reached so NATS pending limits apply back pressure.
+func (s *Subscriber) dispatch(handler pubsub.Handler, m *nats.Msg) {
+	// counted under the lock, so Close never waits while a handler is being added
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.log.Warnw("msg", "nats message dropped, subscriber closed", "subject", m.Subject)
+		return
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	s.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-s.sem
+			s.wg.Done()
+		}()
+		if err := s.handle(handler, m); err != nil {
+			s.log.Errorw("msg", "nats handler failed", "subject", m.Subject, "error", err)
+		}
+	}()
+}
+
+func (s *Subscriber) handle(handler pubsub.Handler, m *nats.Msg) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = uerror.PanicError(r)
+		}
+	}()
+	ctx := s.ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return handler(context.WithValue(ctx, natsMsgKey{}, m), fromNatsMsg(m))
+}
+
+// Close drains the subscriptions, waits for running handlers and drains the connection when owned.
+// Messages still pending after the connection DrainTimeout are dropped.
+func (s *Subscriber) Close() error {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = nil
+	s.mu.Unlock()
+
+	firstErr := s.drain(subs)
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.cancel()
+	if s.owned {
+		if err := s.conn.Drain(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// drain stops the subscriptions and waits until their pending messages are dispatched.
+func (s *Subscriber) drain(subs []*nats.Subscription) error {
+	if len(subs) == 0 {
+		return nil
+	}
+	var firstErr error
+	for _, sub := range subs {
+		if err := sub.Drain(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	// once flushed the server sends nothing more, the barrier runs after every
+	// message already received went through its subscription callback
+	done := make(chan struct{})
+	if err := s.conn.Flush(); err == nil && s.conn.Barrier(func() { close(done) }) == nil {
+		timer := time.NewTimer(s.conn.Opts.DrainTimeout)
+		select {
+		case <-done:
+		case <-timer.C:
+			s.log.Warn("nats subscriber drain timed out")
+		}
+		timer.Stop()
+	}
+	for _, sub := range subs {
+		if sub.IsValid() {
+			_ = sub.Unsubscribe()
+		}
+	}
+	return firstErr
+}
+
+type natsMsgKey struct{}
+
+// natsMsgFromContext returns the received NATS message, responders use it to reply.
+func natsMsgFromContext(ctx context.Context) (*nats.Msg, bool) {
+	m, ok := ctx.Value(natsMsgKey{}).(*nats.Msg)
+	return m, ok
+}
+
+func fromNatsMsg(m *nats.Msg) *pubsub.Message {
+	header := pubsub.Header(m.Header)
+	if header == nil {
+		header = pubsub.Header{}
+	}
+	return &pubsub.Message{
+		Subject: m.Subject,
+		Header:  header,
+		Data:    m.Data,
+	}
+}
@@ -0,0 +1,138 @@
+package nats
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func newSubscriber(t *testing.T, url string, opts ...SubscriberOption) *Subscriber {
+	s, err := NewSubscriber(&conf.Nats{Addr: url}, log.DefaultLogger, opts...)
+	require.Nil(t, err)
+	return s
+}
+
+func publish(t *testing.T, url, subject string, data []byte) {
+	conn, err := nats.Connect(url)
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Nil(t, conn.Publish(subject, data))
+	require.Nil(t, conn.Flush())
+}
+
+func TestSubscriber_Typed(t *testing.T) {
+	srv := runServer(t)
+	s := newSubscriber(t, srv.ClientURL())
+	defer s.Close()
+
+	received := make(chan order, 1)
+	require.Nil(t, pubsub.Subscribe(s, "orders.*", "", func(ctx context.Context, o order) error {
+		received <- o
+		return nil
+	}))
+	require.Nil(t, s.conn.Flush())
+	publish(t, srv.ClientURL(), "orders.created", []byte(`{"id":7}`))
+
+	select {
+	case o := <-received:
+		require.Equal(t, 7, o.ID)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestSubscriber_QueueGroup(t *testing.T) {
+	srv := runServer(t)
+	var first, second int32
+	for _, n := range []*int32{&first, &second} {
+		n := n
+		s := newSubscriber(t, srv.ClientURL())
+		defer s.Close()
+		require.Nil(t, s.Subscribe("orders.created", "workers", func(ctx context.Context, msg *pubsub.Message) error {
+			atomic.AddInt32(n, 1)
+			return nil
+		}))
+		require.Nil(t, s.conn.Flush())
+	}
+
+	for i := 0; i < 50; i++ {
+		publish(t, srv.ClientURL(), "orders.created", nil)
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&first)+atomic.LoadInt32(&second) == 50
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, int32(50), atomic.LoadInt32(&first)+atomic.LoadInt32(&second), "each message is handled once per group")
+}
+
+func TestSubscriber_PanicBecomesError(t *testing.T) {
+	s := NewSubscriberWithConn(nil, log.DefaultLogger)
+	err := s.handle(func(ctx context.Context, msg *pubsub.Message) error {
+		panic("boom")
+	}, &nats.Msg{Subject: "orders.created"})
+	statusErr, ok := err.(*uerror.StatusError)
+	require.True(t, ok)
+	require.Equal(t, int64(http.StatusInternalServerError), statusErr.Code)
+	require.Contains(t, err.Error(), "boom")
+}
+
+func TestSubscriber_CloseWaitsForHandlers(t *testing.T) {
+	srv := runServer(t)
+	s := newSubscriber(t, srv.ClientURL(), WithConcurrency(1))
+
+	var handled int32
+	started := make(chan struct{}, 10)
+	require.Nil(t, s.Subscribe("orders.created", "", func(ctx context.Context, msg *pubsub.Message) error {
+		started <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+	require.Nil(t, s.conn.Flush())
+	for i := 0; i < 3; i++ {
+		publish(t, srv.ClientURL(), "orders.created", nil)
+	}
+	<-started
+
+	require.Nil(t, s.Close())
+	require.Equal(t, int32(3), atomic.LoadInt32(&handled))
+	publish(t, srv.ClientURL(), "orders.created", nil)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, int32(3), atomic.LoadInt32(&handled))
+}
+
+func TestSubscriber_Unsubscribe(t *testing.T) {
+	srv := runServer(t)
+	s := newSubscriber(t, srv.ClientURL())
+	defer s.Close()
+
+	var created, cancelled int32
+	require.Nil(t, s.Subscribe("orders.created", "workers", func(ctx context.Context, msg *pubsub.Message) error {
+		atomic.AddInt32(&created, 1)
+		return nil
+	}))
+	require.Nil(t, s.Subscribe("orders.cancelled", "workers", func(ctx context.Context, msg *pubsub.Message) error {
+		atomic.AddInt32(&cancelled, 1)
+		return nil
+	}))
+	require.Nil(t, pubsub.Unsubscribe(pubsub.WrapSubscriber(s), "orders.created", "workers"))
+
+	publish(t, srv.ClientURL(), "orders.created", nil)
+	publish(t, srv.ClientURL(), "orders.cancelled", nil)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&cancelled) == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, int32(0), atomic.LoadInt32(&created))
+}
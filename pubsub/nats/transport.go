@@ -0,0 +1,48 @@
+package nats
+
+import (
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// KindNATS is the transport kind of NATS message handlers.
+const KindNATS transport.Kind = "nats"
+
+var _ transport.Transporter = (*Transport)(nil)
+
+// Transport is a NATS server transport, the operation is the message subject.
+// For a JetStream Consumer the endpoint is the consumer filter subject and the
+// queue group its durable name.
+type Transport struct {
+	endpoint    string
+	operation   string
+	queueGroup  string
+	reqHeader   pubsub.Header
+	replyHeader pubsub.Header
+}
+
+func (tr *Transport) Kind() transport.Kind {
+	return KindNATS
+}
+
+func (tr *Transport) Endpoint() string {
+	return tr.endpoint
+}
+
+func (tr *Transport) Operation() string {
+	return tr.operation
+}
+
+// QueueGroup returns the queue group the handler was registered with.
+func (tr *Transport) QueueGroup() string {
+	return tr.queueGroup
+}
+
+func (tr *Transport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+func (tr *Transport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
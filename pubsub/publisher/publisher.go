@@ -1,29 +1,48 @@
+// Package publisher is a thin NATS publisher.
+//
+// Deprecated: use pubsub.Publisher implemented by the pubsub/nats package instead.
 package publisher
 
 import (
 	"encoding/json"
-	"github.com/nats-io/nats.go"
 	"log"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nartvt/go-core/pubsub"
 )
 
 type NATSPublisher struct {
-	nc    *nats.Conn
-	topic string
+	nc     *nats.Conn
+	topic  string
+	codecs *pubsub.Codecs
 }
 
+// NewPublisher connects to NATS, the caller owns the connection and must Close the publisher.
+// A failed connection is logged and every Publish returns nats.ErrInvalidConnection,
+// use Connect to get the error.
 func NewPublisher(host string, topic string) *NATSPublisher {
+	publisher, err := Connect(host, topic)
+	if err != nil {
+		log.Printf("Error connecting to NATS: %v", err)
+		return NewNATSPublisher(nil, topic)
+	}
+	return publisher
+}
+
+// Connect connects to NATS like NewPublisher and returns the connection error.
+func Connect(host string, topic string) (*NATSPublisher, error) {
 	// Connect to the NATS server
 	//nc, err := nats.Connect("nats://localhost:4222")
 	nc, err := nats.Connect(host)
 	if err != nil {
-		log.Fatalf("Error connecting to NATS: %v", err)
+		return nil, err
 	}
-	defer nc.Close()
 
 	// Create a publisher
 	publisher := NewNATSPublisher(nc, topic)
 
-	return publisher
+	return publisher, nil
 }
 
 // NATSPublisher is a simple wrapper around the NATS connection for publishing messages.
@@ -33,14 +52,32 @@ func NewNATSPublisher(nc *nats.Conn, topic string) *NATSPublisher {
 	return &NATSPublisher{nc: nc, topic: topic}
 }
 
-// Publish publishes a message to a given subject.
+// WithCodecs encodes published messages with the codec codecs selects for the
+// subject and sends its content type header, usually with pubsub.DefaultCodecs.
+// Without it messages are json encoded and sent without headers. Proto messages
+// are then encoded with protojson and headers need a NATS server 2.2 or later.
+func (p *NATSPublisher) WithCodecs(codecs *pubsub.Codecs) *NATSPublisher {
+	p.codecs = codecs
+	return p
+}
+
+// Publish publishes a message to a given subject, it is json encoded unless
+// codecs are set by WithCodecs.
 func (p *NATSPublisher) Publish(subject string, msg interface{}) error {
-	msgByte, err := json.Marshal(msg)
+	if p.codecs == nil {
+		msgByte, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return p.nc.Publish(subject, msgByte)
+	}
+
+	m, err := p.codecs.Encode(subject, msg)
 	if err != nil {
 		return err
 	}
 
-	return p.nc.Publish(subject, msgByte)
+	return p.nc.PublishMsg(&nats.Msg{Subject: subject, Header: nats.Header(m.Header), Data: m.Data})
 }
 
 // Close closes the NATS connection (optional).
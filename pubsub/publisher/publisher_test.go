@@ -0,0 +1,41 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPublisher(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	srv := natstest.RunServer(&opts)
+	defer srv.Shutdown()
+
+	sub, err := nats.Connect(srv.ClientURL())
+	require.Nil(t, err)
+	defer sub.Close()
+	ch := make(chan *nats.Msg, 1)
+	_, err = sub.ChanSubscribe("orders.created", ch)
+	require.Nil(t, err)
+	require.Nil(t, sub.Flush())
+
+	p := NewPublisher(srv.ClientURL(), "orders.created")
+	defer p.Close()
+	require.Nil(t, p.Publish("orders.created", map[string]int{"id": 1}))
+	select {
+	case m := <-ch:
+		require.JSONEq(t, `{"id":1}`, string(m.Data))
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	_, err = Connect("nats://127.0.0.1:1", "orders.created")
+	require.NotNil(t, err)
+	p = NewPublisher("nats://127.0.0.1:1", "orders.created")
+	require.Equal(t, nats.ErrInvalidConnection, p.Publish("orders.created", 1))
+	p.Close()
+}
@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Header carries message metadata, keys are case sensitive like NATS headers.
+// It implements transport.Header.
+type Header map[string][]string
+
+func (h Header) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (h Header) Set(key, value string) {
+	h[key] = []string{value}
+}
+
+func (h Header) Add(key, value string) {
+	h[key] = append(h[key], value)
+}
+
+func (h Header) Del(key string) {
+	delete(h, key)
+}
+
+func (h Header) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (h Header) Values(key string) []string {
+	return h[key]
+}
+
+// Message is a broker independent message, Subject is only set on received messages.
+type Message struct {
+	Subject string
+	Header  Header
+	Data    []byte
+}
+
+func NewMessage(data []byte) *Message {
+	return &Message{Header: Header{}, Data: data}
+}
+
+// NewJSONMessage json encodes v into a new message.
+func NewJSONMessage(v interface{}) (*Message, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return NewMessage(data), nil
+}
+
+// Publisher publishes messages to a topic, Close releases the broker connection.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msg *Message) error
+	io.Closer
+}
@@ -0,0 +1,54 @@
+// Package redis implements pubsub.Publisher and pubsub.Subscriber on redis:
+// PUBLISH/SUBSCRIBE for fire-and-forget delivery and Streams with consumer
+// groups for durable delivery. Channels and streams are named with the key
+// builder of the redisdb.RedisClient.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+var _ pubsub.Publisher = (*Publisher)(nil)
+
+// envelope carries the message headers over PUBLISH, which has no header support.
+type envelope struct {
+	Header pubsub.Header `json:"header,omitempty"`
+	Data   []byte        `json:"data"`
+}
+
+// Publisher publishes with PUBLISH, subscribers that are not connected miss the message.
+type Publisher struct {
+	client *redisdb.RedisClient
+	owned  bool
+}
+
+// NewPublisher connects to redis, the connection is closed by Close.
+func NewPublisher(c *conf.Redis) *Publisher {
+	return &Publisher{client: redisdb.NewRedisClient(c), owned: true}
+}
+
+// NewPublisherWithClient publishes on a client owned by the caller.
+func NewPublisherWithClient(client *redisdb.RedisClient) *Publisher {
+	return &Publisher{client: client}
+}
+
+func (p *Publisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	data, err := json.Marshal(envelope{Header: msg.Header, Data: msg.Data})
+	if err != nil {
+		return err
+	}
+	return p.client.GetClient().Publish(ctx, p.client.KeyBuilder().Key(topic), data).Err()
+}
+
+// Close closes the connection when the publisher owns it.
+func (p *Publisher) Close() error {
+	if !p.owned {
+		return nil
+	}
+	return p.client.GetClient().Close()
+}
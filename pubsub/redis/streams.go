@@ -0,0 +1,344 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/nats-io/nuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+// Fields of a stream entry.
+const (
+	fieldData   = "data"
+	fieldHeader = "header"
+)
+
+// Headers set on entries moved to the dead-letter stream.
+const (
+	DeadLetterErrorHeader      = "Dead-Letter-Error"
+	DeadLetterStreamHeader     = "Dead-Letter-Stream"
+	DeadLetterGroupHeader      = "Dead-Letter-Group"
+	DeadLetterDeliveriesHeader = "Dead-Letter-Deliveries"
+)
+
+const (
+	defaultBlock        = 2 * time.Second
+	defaultBatchSize    = 16
+	defaultClaimMinIdle = time.Minute
+)
+
+var (
+	_ pubsub.Publisher  = (*StreamPublisher)(nil)
+	_ pubsub.Subscriber = (*StreamSubscriber)(nil)
+)
+
+// StreamPublisher appends messages to redis streams with XADD, the topic is the stream key.
+type StreamPublisher struct {
+	client *redisdb.RedisClient
+	owned  bool
+	maxLen int64
+}
+
+// NewStreamPublisher connects to redis, the connection is closed by Close.
+func NewStreamPublisher(c *conf.Redis, sc *conf.PubSub_RedisStreams) *StreamPublisher {
+	p := NewStreamPublisherWithClient(redisdb.NewRedisClient(c), sc)
+	p.owned = true
+	return p
+}
+
+// NewStreamPublisherWithClient publishes on a client owned by the caller.
+func NewStreamPublisherWithClient(client *redisdb.RedisClient, sc *conf.PubSub_RedisStreams) *StreamPublisher {
+	return &StreamPublisher{client: client, maxLen: sc.GetMaxLen()}
+}
+
+func (p *StreamPublisher) Publish(ctx context.Context, topic string, msg *pubsub.Message) error {
+	values := map[string]interface{}{fieldData: msg.Data}
+	if len(msg.Header) > 0 {
+		header, err := json.Marshal(msg.Header)
+		if err != nil {
+			return err
+		}
+		values[fieldHeader] = header
+	}
+	return p.client.GetClient().XAdd(ctx, &redis.XAddArgs{
+		Stream: p.client.KeyBuilder().Key(topic),
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: values,
+	}).Err()
+}
+
+// Close closes the connection when the publisher owns it.
+func (p *StreamPublisher) Close() error {
+	if !p.owned {
+		return nil
+	}
+	return p.client.GetClient().Close()
+}
+
+// StreamSubscriber consumes redis streams with consumer groups. Subscribers sharing
+// a queue group share the entries, without queue group every subscriber gets its own
+// group and receives every entry published after it subscribed. Entries are
+// acknowledged when the handler succeeds. Other failures stay pending and are
+// claimed again after claim_min_idle, until a terminal error (see pubsub.IsTerminal)
+// or the max_deliver delivery moves the entry to dead_letter_stream and acknowledges it.
+type StreamSubscriber struct {
+	client       *redisdb.RedisClient
+	owned        bool
+	log          *log.Helper
+	group        string
+	consumer     string
+	block        time.Duration
+	batchSize    int64
+	claimMinIdle time.Duration
+	maxDeliver   int64
+	deadLetter   string
+
+	// reads stop with stopCtx, handlers and acks run with ctx until they returned
+	stopCtx context.Context
+	stop    context.CancelFunc
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	private map[string]string // private group -> stream, destroyed by Close
+}
+
+// NewStreamSubscriber connects to redis, the connection is closed by Close.
+func NewStreamSubscriber(c *conf.Redis, sc *conf.PubSub_RedisStreams, logger log.Logger) *StreamSubscriber {
+	s := NewStreamSubscriberWithClient(redisdb.NewRedisClient(c), sc, logger)
+	s.owned = true
+	return s
+}
+
+// NewStreamSubscriberWithClient consumes on a client owned by the caller.
+func NewStreamSubscriberWithClient(client *redisdb.RedisClient, sc *conf.PubSub_RedisStreams, logger log.Logger) *StreamSubscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCtx, stop := context.WithCancel(ctx)
+	hostname, _ := os.Hostname()
+	s := &StreamSubscriber{
+		client:       client,
+		log:          log.NewHelper(logger),
+		group:        sc.GetConsumerGroup(),
+		consumer:     hostname + "-" + nuid.Next(),
+		block:        defaultBlock,
+		batchSize:    defaultBatchSize,
+		claimMinIdle: defaultClaimMinIdle,
+		maxDeliver:   int64(sc.GetMaxDeliver()),
+		deadLetter:   sc.GetDeadLetterStream(),
+		stopCtx:      stopCtx,
+		stop:         stop,
+		ctx:          ctx,
+		cancel:       cancel,
+		private:      map[string]string{},
+	}
+	if sc.GetBlock() != nil {
+		s.block = sc.Block.AsDuration()
+	}
+	if sc.GetBatchSize() > 0 {
+		s.batchSize = int64(sc.BatchSize)
+	}
+	if sc.GetClaimMinIdle() != nil {
+		s.claimMinIdle = sc.ClaimMinIdle.AsDuration()
+	}
+	return s
+}
+
+// Subscribe consumes the stream subject, an empty queueGroup falls back to the configured consumer group.
+func (s *StreamSubscriber) Subscribe(subject, queueGroup string, handler pubsub.Handler) error {
+	group, start := queueGroup, "0"
+	if len(group) == 0 {
+		group = s.group
+	}
+	stream := s.client.KeyBuilder().Key(subject)
+	if len(group) == 0 {
+		// private group, only entries published from now on are delivered
+		group, start = "sub-"+nuid.Next(), "$"
+		s.mu.Lock()
+		s.private[group] = stream
+		s.mu.Unlock()
+	}
+	err := s.client.GetClient().XGroupCreateMkStream(s.ctx, stream, group, start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.consume(subject, stream, group, handler)
+	}()
+	return nil
+}
+
+func (s *StreamSubscriber) consume(subject, stream, group string, handler pubsub.Handler) {
+	rdb := s.client.GetClient()
+	lastClaim := time.Now()
+	for s.stopCtx.Err() == nil {
+		if time.Since(lastClaim) >= s.claimMinIdle {
+			lastClaim = time.Now()
+			s.claim(subject, stream, group, handler)
+		}
+
+		streams, err := rdb.XReadGroup(s.stopCtx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: s.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    s.batchSize,
+			Block:    s.block,
+		}).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && s.stopCtx.Err() == nil {
+				s.log.Errorw("msg", "redis stream read failed", "stream", stream, "error", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		for _, st := range streams {
+			s.process(subject, stream, group, handler, st.Messages)
+		}
+	}
+}
+
+// claim takes over the entries idle for claimMinIdle, following the XAUTOCLAIM cursor
+// through the whole pending list.
+func (s *StreamSubscriber) claim(subject, stream, group string, handler pubsub.Handler) {
+	rdb := s.client.GetClient()
+	start := "0-0"
+	for s.stopCtx.Err() == nil {
+		claimed, next, err := rdb.XAutoClaim(s.stopCtx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    group,
+			Consumer: s.consumer,
+			MinIdle:  s.claimMinIdle,
+			Start:    start,
+			Count:    s.batchSize,
+		}).Result()
+		if err != nil {
+			if s.stopCtx.Err() == nil {
+				s.log.Errorw("msg", "redis stream claim failed", "stream", stream, "error", err)
+			}
+			return
+		}
+		s.process(subject, stream, group, handler, claimed)
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+func (s *StreamSubscriber) process(subject, stream, group string, handler pubsub.Handler, entries []redis.XMessage) {
+	for _, entry := range entries {
+		err := s.handle(handler, subject, entry)
+		if err != nil {
+			delivered := s.deliveries(stream, group, entry.ID)
+			if !pubsub.IsTerminal(err) && (s.maxDeliver <= 0 || delivered < s.maxDeliver) {
+				s.log.Warnw("msg", "redis stream handler failed, retrying", "stream", stream, "id", entry.ID, "deliveries", delivered, "error", err)
+				continue
+			}
+			s.log.Errorw("msg", "redis stream entry dead-lettered", "stream", stream, "id", entry.ID, "deliveries", delivered, "error", err)
+			if err := s.deadLetterEntry(stream, group, entry, delivered, err); err != nil {
+				s.log.Errorw("msg", "redis stream dead-letter failed", "stream", s.deadLetter, "id", entry.ID, "error", err)
+				continue
+			}
+		}
+		if err := s.client.GetClient().XAck(s.ctx, stream, group, entry.ID).Err(); err != nil {
+			s.log.Errorw("msg", "redis stream ack failed", "stream", stream, "id", entry.ID, "error", err)
+		}
+	}
+}
+
+// deliveries returns how many times the pending entry was delivered, 1 when unknown.
+func (s *StreamSubscriber) deliveries(stream, group, id string) int64 {
+	pending, err := s.client.GetClient().XPendingExt(s.ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 1
+	}
+	return pending[0].RetryCount
+}
+
+// deadLetterEntry appends the entry with error metadata to the dead-letter stream,
+// without one the entry is only acknowledged.
+func (s *StreamSubscriber) deadLetterEntry(stream, group string, entry redis.XMessage, delivered int64, cause error) error {
+	if len(s.deadLetter) == 0 {
+		return nil
+	}
+	header := pubsub.Header{}
+	if raw, ok := entry.Values[fieldHeader].(string); ok {
+		// an undecodable header is dropped, the error says why the entry failed
+		_ = json.Unmarshal([]byte(raw), &header)
+	}
+	header.Set(DeadLetterErrorHeader, cause.Error())
+	header.Set(DeadLetterStreamHeader, stream)
+	header.Set(DeadLetterGroupHeader, group)
+	header.Set(DeadLetterDeliveriesHeader, strconv.FormatInt(delivered, 10))
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return s.client.GetClient().XAdd(s.ctx, &redis.XAddArgs{
+		Stream: s.client.KeyBuilder().Key(s.deadLetter),
+		Values: map[string]interface{}{fieldData: entry.Values[fieldData], fieldHeader: data},
+	}).Err()
+}
+
+func (s *StreamSubscriber) handle(handler pubsub.Handler, subject string, entry redis.XMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = uerror.PanicError(r)
+		}
+	}()
+	msg := &pubsub.Message{Subject: subject, Header: pubsub.Header{}}
+	if data, ok := entry.Values[fieldData].(string); ok {
+		msg.Data = []byte(data)
+	}
+	if header, ok := entry.Values[fieldHeader].(string); ok {
+		if err := json.Unmarshal([]byte(header), &msg.Header); err != nil {
+			return pubsub.Terminal(err)
+		}
+	}
+	return handler(s.ctx, msg)
+}
+
+// Close stops consuming, waits for running handlers and closes the connection when owned.
+func (s *StreamSubscriber) Close() error {
+	s.stop()
+	s.wg.Wait()
+
+	var firstErr error
+	s.mu.Lock()
+	for group, stream := range s.private {
+		if err := s.client.GetClient().XGroupDestroy(s.ctx, stream, group).Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.private = map[string]string{}
+	s.mu.Unlock()
+	s.cancel()
+	if s.owned {
+		if err := s.client.GetClient().Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
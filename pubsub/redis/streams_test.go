@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func newStreams(t *testing.T, sc *conf.PubSub_RedisStreams) (*redisdb.RedisClient, *StreamPublisher, *StreamSubscriber) {
+	mr := miniredis.RunT(t)
+	client := redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { _ = client.GetClient().Close() })
+	sc.Block = durationpb.New(10 * time.Millisecond)
+	sc.ClaimMinIdle = durationpb.New(20 * time.Millisecond)
+	return client, NewStreamPublisherWithClient(client, sc), NewStreamSubscriberWithClient(client, sc, log.DefaultLogger)
+}
+
+func pending(t *testing.T, client *redisdb.RedisClient, stream, group string) int64 {
+	p, err := client.GetClient().XPending(context.Background(), client.KeyBuilder().Key(stream), group).Result()
+	require.Nil(t, err)
+	return p.Count
+}
+
+func TestStreams_ConsumeAndAck(t *testing.T) {
+	client, p, s := newStreams(t, &conf.PubSub_RedisStreams{ConsumerGroup: "billing"})
+	received := make(chan *pubsub.Message, 2)
+	require.Nil(t, s.Subscribe("orders", "", func(ctx context.Context, msg *pubsub.Message) error {
+		received <- msg
+		return nil
+	}))
+
+	msg := pubsub.NewMessage([]byte("o-1"))
+	msg.Header.Set("trace-id", "t-1")
+	require.Nil(t, p.Publish(context.Background(), "orders", msg))
+	select {
+	case m := <-received:
+		require.Equal(t, "orders", m.Subject)
+		require.Equal(t, "o-1", string(m.Data))
+		require.Equal(t, "t-1", m.Header.Get("trace-id"))
+	case <-time.After(time.Second):
+		t.Fatal("entry not consumed")
+	}
+	require.Nil(t, s.Close())
+	require.Equal(t, int64(0), pending(t, client, "orders", "billing"))
+}
+
+func TestStreams_ReclaimsFailedEntries(t *testing.T) {
+	client, p, s := newStreams(t, &conf.PubSub_RedisStreams{ConsumerGroup: "billing"})
+	var attempts int32
+	done := make(chan struct{})
+	require.Nil(t, s.Subscribe("orders", "", func(ctx context.Context, msg *pubsub.Message) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("db down")
+		}
+		close(done)
+		return nil
+	}))
+
+	require.Nil(t, p.Publish(context.Background(), "orders", pubsub.NewMessage([]byte("o-1"))))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("entry not reclaimed")
+	}
+	require.Nil(t, s.Close())
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	require.Equal(t, int64(0), pending(t, client, "orders", "billing"))
+}
+
+func TestStreams_DeadLetter(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		err        error
+		deliveries string
+	}{
+		{name: "max deliver", err: errors.New("db down"), deliveries: "3"},
+		{name: "terminal", err: pubsub.Terminal(errors.New("bad payload")), deliveries: "1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, p, s := newStreams(t, &conf.PubSub_RedisStreams{ConsumerGroup: "billing", MaxDeliver: 3, DeadLetterStream: "orders.dlq"})
+			var attempts int32
+			require.Nil(t, s.Subscribe("orders", "", func(ctx context.Context, msg *pubsub.Message) error {
+				atomic.AddInt32(&attempts, 1)
+				return tc.err
+			}))
+			require.Nil(t, p.Publish(context.Background(), "orders", pubsub.NewMessage([]byte("o-1"))))
+
+			dlq := client.KeyBuilder().Key("orders.dlq")
+			require.Eventually(t, func() bool {
+				n, _ := client.GetClient().XLen(context.Background(), dlq).Result()
+				return n == 1
+			}, 2*time.Second, 5*time.Millisecond)
+			require.Nil(t, s.Close())
+
+			entries, err := client.GetClient().XRange(context.Background(), dlq, "-", "+").Result()
+			require.Nil(t, err)
+			require.Len(t, entries, 1)
+			require.Equal(t, "o-1", entries[0].Values[fieldData])
+			header := pubsub.Header{}
+			require.Nil(t, json.Unmarshal([]byte(entries[0].Values[fieldHeader].(string)), &header))
+			require.Equal(t, tc.err.Error(), header.Get(DeadLetterErrorHeader))
+			require.Equal(t, client.KeyBuilder().Key("orders"), header.Get(DeadLetterStreamHeader))
+			require.Equal(t, "billing", header.Get(DeadLetterGroupHeader))
+			require.Equal(t, tc.deliveries, header.Get(DeadLetterDeliveriesHeader))
+			require.Equal(t, tc.deliveries, strconv.Itoa(int(atomic.LoadInt32(&attempts))))
+			require.Equal(t, int64(0), pending(t, client, "orders", "billing"))
+		})
+	}
+}
+
+// claimStarts records the start id of every XAUTOCLAIM.
+type claimStarts struct {
+	mu     sync.Mutex
+	starts []string
+}
+
+func (c *claimStarts) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (c *claimStarts) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if args := cmd.Args(); strings.EqualFold(cmd.Name(), "xautoclaim") && len(args) > 5 {
+			c.mu.Lock()
+			c.starts = append(c.starts, args[5].(string))
+			c.mu.Unlock()
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (c *claimStarts) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestStreams_ClaimFollowsTheCursor(t *testing.T) {
+	client, p, s := newStreams(t, &conf.PubSub_RedisStreams{ConsumerGroup: "billing", BatchSize: 1})
+	ctx := context.Background()
+	stream := client.KeyBuilder().Key("orders")
+	rdb := client.GetClient()
+	require.Nil(t, rdb.XGroupCreateMkStream(ctx, stream, "billing", "0").Err())
+	for i := 0; i < 3; i++ {
+		require.Nil(t, p.Publish(ctx, "orders", pubsub.NewMessage([]byte(strconv.Itoa(i)))))
+	}
+	// a crashed consumer read the entries without acking them
+	streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "billing", Consumer: "crashed", Streams: []string{stream, ">"}}).Result()
+	require.Nil(t, err)
+	entries := streams[0].Messages
+	time.Sleep(30 * time.Millisecond)
+
+	starts := &claimStarts{}
+	rdb.AddHook(starts)
+	var handled int32
+	s.claim("orders", stream, "billing", func(ctx context.Context, msg *pubsub.Message) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	// the second page starts at the cursor returned by the first one, miniredis reads
+	// the start id as exclusive unlike redis so the number of pages is not asserted
+	require.GreaterOrEqual(t, len(starts.starts), 2)
+	require.Equal(t, []string{"0-0", entries[1].ID}, starts.starts[:2])
+	require.GreaterOrEqual(t, atomic.LoadInt32(&handled), int32(2))
+}
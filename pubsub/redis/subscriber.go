@@ -0,0 +1,149 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+var _ pubsub.Subscriber = (*Subscriber)(nil)
+
+// ErrQueueGroup is returned when subscribing with a queue group, redis PUBLISH
+// delivers every message to every subscriber. Use the streams subscriber instead.
+var ErrQueueGroup = errors.New("redis: queue groups are not supported by pub/sub, use streams")
+
+// Subscriber runs handlers for messages received with SUBSCRIBE, subjects with the
+// NATS wildcards * and > are subscribed with PSUBSCRIBE.
+type Subscriber struct {
+	client *redisdb.RedisClient
+	owned  bool
+	log    *log.Helper
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	subs   []*redis.PubSub
+}
+
+// NewSubscriber connects to redis, the connection is closed by Close.
+func NewSubscriber(c *conf.Redis, logger log.Logger) *Subscriber {
+	s := NewSubscriberWithClient(redisdb.NewRedisClient(c), logger)
+	s.owned = true
+	return s
+}
+
+// NewSubscriberWithClient subscribes on a client owned by the caller.
+func NewSubscriberWithClient(client *redisdb.RedisClient, logger log.Logger) *Subscriber {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Subscriber{
+		client: client,
+		log:    log.NewHelper(logger),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *Subscriber) Subscribe(subject, queueGroup string, handler pubsub.Handler) error {
+	if len(queueGroup) > 0 {
+		return ErrQueueGroup
+	}
+	keys := s.client.KeyBuilder()
+	channel := keys.Key(subject)
+	var ps *redis.PubSub
+	if strings.ContainsAny(subject, "*>") {
+		ps = s.client.GetClient().PSubscribe(s.ctx, keys.Match(globPattern(subject)))
+	} else {
+		ps = s.client.GetClient().Subscribe(s.ctx, channel)
+	}
+	// wait for the subscription to be confirmed so no message published afterwards is missed
+	if _, err := ps.Receive(s.ctx); err != nil {
+		_ = ps.Close()
+		return err
+	}
+
+	s.mu.Lock()
+	s.subs = append(s.subs, ps)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for m := range ps.Channel() {
+			topic := keys.Trim(m.Channel)
+			// the glob * also matches dots, keep the NATS semantics
+			if !pubsub.MatchSubject(subject, topic) {
+				continue
+			}
+			if err := s.handle(handler, topic, m.Payload); err != nil {
+				s.log.Errorw("msg", "redis handler failed", "subject", topic, "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Subscriber) handle(handler pubsub.Handler, topic, payload string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = uerror.PanicError(r)
+		}
+	}()
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		return err
+	}
+	if env.Header == nil {
+		env.Header = pubsub.Header{}
+	}
+	return handler(s.ctx, &pubsub.Message{Subject: topic, Header: env.Header, Data: env.Data})
+}
+
+// Close unsubscribes, waits for running handlers and closes the connection when owned.
+func (s *Subscriber) Close() error {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = nil
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, ps := range subs {
+		if err := ps.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.wg.Wait()
+	s.cancel()
+	if s.owned {
+		if err := s.client.GetClient().Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// globPattern converts NATS wildcards to a redis glob pattern, other tokens are
+// matched literally.
+func globPattern(subject string) string {
+	tokens := strings.Split(subject, ".")
+	for i, t := range tokens {
+		switch t {
+		case "*":
+		case ">":
+			tokens[i] = "*"
+		default:
+			tokens[i] = redisdb.EscapePattern(t)
+		}
+	}
+	return strings.Join(tokens, ".")
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+func TestGlobPattern(t *testing.T) {
+	require.Equal(t, "orders.*.created", globPattern("orders.*.created"))
+	require.Equal(t, "orders.*", globPattern("orders.>"))
+	require.Equal(t, "orders.created", globPattern("orders.created"))
+}
+
+func TestGlobPattern_EscapesLiteralTokens(t *testing.T) {
+	require.Equal(t, `orders.a\?b.*`, globPattern("orders.a?b.>"))
+}
+
+func TestSubscriber_WildcardStaysInNamespace(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	client := redisdb.WrapClient(rdb).WithKeyBuilder(redisdb.NewKeyBuilder("shop", 1).Tenant("a*"))
+	s := NewSubscriberWithClient(client, log.DefaultLogger)
+	defer s.Close()
+
+	received := make(chan string, 2)
+	require.Nil(t, s.Subscribe("orders.>", "", func(ctx context.Context, msg *pubsub.Message) error {
+		received <- msg.Subject
+		return nil
+	}))
+	other := redisdb.WrapClient(rdb).WithKeyBuilder(redisdb.NewKeyBuilder("shop", 1).Tenant("ab"))
+	require.Nil(t, NewPublisherWithClient(other).Publish(context.Background(), "orders.created", pubsub.NewMessage(nil)))
+	require.Nil(t, NewPublisherWithClient(client).Publish(context.Background(), "orders.paid", pubsub.NewMessage(nil)))
+
+	select {
+	case subject := <-received:
+		require.Equal(t, "orders.paid", subject)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+	select {
+	case subject := <-received:
+		t.Fatalf("received %q from another namespace", subject)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,477 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nuid"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+const (
+	defaultStepTimeout          = 30 * time.Second
+	defaultInterval             = time.Second
+	defaultBatchSize            = 100
+	defaultCompensationAttempts = 3
+	defaultQueueGroup           = "saga"
+	maxConflicts                = 5
+)
+
+var _ transport.Server = (*Orchestrator)(nil)
+
+// Option is orchestrator option.
+type Option func(*Orchestrator)
+
+// WithStepTimeout sets how long a step waits for its reply, default 30s.
+func WithStepTimeout(timeout time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.stepTimeout = timeout
+	}
+}
+
+// WithInterval sets how often timed out sagas are looked up.
+func WithInterval(interval time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.interval = interval
+	}
+}
+
+// WithCompensationAttempts bounds the sends of a compensation without reply before
+// the saga fails, default 3.
+func WithCompensationAttempts(n int) Option {
+	return func(o *Orchestrator) {
+		o.compensationAttempts = n
+	}
+}
+
+// WithQueueGroup sets the queue group orchestrators share the replies in, default saga.
+func WithQueueGroup(group string) Option {
+	return func(o *Orchestrator) {
+		o.queueGroup = group
+	}
+}
+
+// WithCodecs selects the command codec per topic, default is pubsub.DefaultCodecs.
+func WithCodecs(codecs *pubsub.Codecs) Option {
+	return func(o *Orchestrator) {
+		o.codecs = codecs
+	}
+}
+
+// WithLogger with orchestrator logger.
+func WithLogger(logger log.Logger) Option {
+	return func(o *Orchestrator) {
+		o.log = log.NewHelper(logger)
+	}
+}
+
+// Orchestrator runs sagas. It is a kratos transport.Server: Start subscribes to the
+// reply topic and looks up timed out sagas, so every pod can run an orchestrator on the
+// same store. Transitions are saved with optimistic locking before the command is sent,
+// a command lost by the publisher is handled like a timeout.
+type Orchestrator struct {
+	store                Store
+	publisher            pubsub.Publisher
+	subscriber           pubsub.Subscriber
+	replyTopic           string
+	queueGroup           string
+	codecs               *pubsub.Codecs
+	log                  *log.Helper
+	stepTimeout          time.Duration
+	interval             time.Duration
+	compensationAttempts int
+
+	mu         sync.Mutex
+	sagas      map[string]*Definition
+	subscribed bool
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewOrchestrator sends commands through publisher and receives the replies on
+// replyTopic from subscriber.
+func NewOrchestrator(store Store, publisher pubsub.Publisher, subscriber pubsub.Subscriber, replyTopic string, opts ...Option) *Orchestrator {
+	o := &Orchestrator{
+		store:                store,
+		publisher:            publisher,
+		subscriber:           subscriber,
+		replyTopic:           replyTopic,
+		queueGroup:           defaultQueueGroup,
+		codecs:               pubsub.DefaultCodecs,
+		log:                  log.NewHelper(log.GetLogger()),
+		stepTimeout:          defaultStepTimeout,
+		interval:             defaultInterval,
+		compensationAttempts: defaultCompensationAttempts,
+		sagas:                map[string]*Definition{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Register declares a saga, every orchestrator sharing the store must register it.
+func (o *Orchestrator) Register(def Definition) error {
+	if len(def.Name) == 0 || len(def.Steps) == 0 {
+		return errors.New("saga: a definition needs a name and steps")
+	}
+	for _, step := range def.Steps {
+		if step.Action == nil {
+			return fmt.Errorf("saga: step %s of %s has no action", step.Name, def.Name)
+		}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.sagas[def.Name]; ok {
+		return fmt.Errorf("saga: %s is already registered", def.Name)
+	}
+	o.sagas[def.Name] = &def
+	return nil
+}
+
+func (o *Orchestrator) definition(name string) (*Definition, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	def, ok := o.sagas[name]
+	if !ok {
+		return nil, fmt.Errorf("saga: %s is not registered", name)
+	}
+	return def, nil
+}
+
+// Begin starts a saga with data and sends the command of its first step.
+func (o *Orchestrator) Begin(ctx context.Context, name string, data interface{}) (*Instance, error) {
+	def, err := o.definition(name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	inst := &Instance{ID: nuid.Next(), Name: name, CreatedAt: now, UpdatedAt: now}
+	if err := inst.Encode(data); err != nil {
+		return nil, err
+	}
+	cmd, err := o.forward(ctx, def, inst, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.store.Create(ctx, inst); err != nil {
+		return nil, err
+	}
+	o.send(ctx, inst, cmd)
+	return inst, nil
+}
+
+// Get returns the state of a saga.
+func (o *Orchestrator) Get(ctx context.Context, id string) (*Instance, error) {
+	return o.store.Get(ctx, id)
+}
+
+// forward moves inst to the action of step i, or to the compensations when an action
+// cannot be built. It returns the command to send, nil when the saga is done.
+func (o *Orchestrator) forward(ctx context.Context, def *Definition, inst *Instance, i int) (*Command, error) {
+	for ; i < len(def.Steps); i++ {
+		step := def.Steps[i]
+		cmd, err := step.Action(ctx, inst)
+		if err != nil {
+			inst.Error = fmt.Sprintf("step %s: %v", step.Name, err)
+			return o.backward(ctx, def, inst, i-1)
+		}
+		if cmd != nil {
+			o.wait(inst, StatusRunning, i, step.Timeout)
+			return cmd, nil
+		}
+	}
+	o.finish(inst, StatusCompleted)
+	return nil, nil
+}
+
+// backward moves inst to the compensation of step i or the last completed step before it.
+func (o *Orchestrator) backward(ctx context.Context, def *Definition, inst *Instance, i int) (*Command, error) {
+	for ; i >= 0; i-- {
+		step := def.Steps[i]
+		if step.Compensation == nil {
+			continue
+		}
+		cmd, err := step.Compensation(ctx, inst)
+		if err != nil {
+			inst.Error = fmt.Sprintf("compensation of step %s: %v", step.Name, err)
+			o.finish(inst, StatusFailed)
+			return nil, nil
+		}
+		if cmd != nil {
+			o.wait(inst, StatusCompensating, i, step.Timeout)
+			return cmd, nil
+		}
+	}
+	o.finish(inst, StatusCompensated)
+	return nil, nil
+}
+
+func (o *Orchestrator) wait(inst *Instance, status Status, step int, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = o.stepTimeout
+	}
+	if inst.Status != status || inst.Step != step {
+		inst.Attempts = 0
+	}
+	inst.Status = status
+	inst.Step = step
+	inst.Attempts++
+	inst.Deadline = time.Now().UTC().Add(timeout)
+}
+
+func (o *Orchestrator) finish(inst *Instance, status Status) {
+	inst.Status = status
+	inst.Deadline = time.Time{}
+	if status != StatusFailed {
+		o.log.Infow("msg", "saga finished", "saga", inst.Name, "id", inst.ID, "status", status, "error", inst.Error)
+		return
+	}
+	o.log.Errorw("msg", "saga failed", "saga", inst.Name, "id", inst.ID, "step", inst.Step, "error", inst.Error)
+}
+
+// send publishes the command of the step inst waits for, a failure is only logged
+// since the step times out.
+func (o *Orchestrator) send(ctx context.Context, inst *Instance, cmd *Command) {
+	if cmd == nil {
+		return
+	}
+	msg, err := o.codecs.Encode(cmd.Topic, cmd.Value)
+	if err == nil {
+		phase := PhaseAction
+		if inst.Status == StatusCompensating {
+			phase = PhaseCompensation
+		}
+		msg.Header.Set(IDHeader, inst.ID)
+		msg.Header.Set(StepHeader, strconv.Itoa(inst.Step))
+		msg.Header.Set(PhaseHeader, phase)
+		msg.Header.Set(ReplyToHeader, o.replyTopic)
+		err = o.publisher.Publish(ctx, cmd.Topic, msg)
+	}
+	if err != nil {
+		o.log.Errorw("msg", "saga command not sent", "saga", inst.Name, "id", inst.ID, "step", inst.Step, "topic", cmd.Topic, "error", err)
+	}
+}
+
+// transition applies fn to the stored saga id and saves it, fn returns false to leave
+// the saga unchanged. It is retried on concurrent updates.
+func (o *Orchestrator) transition(ctx context.Context, id string, fn func(def *Definition, inst *Instance) (bool, *Command, error)) error {
+	for attempt := 0; ; attempt++ {
+		inst, err := o.store.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		def, err := o.definition(inst.Name)
+		if err != nil {
+			return err
+		}
+		changed, cmd, err := fn(def, inst)
+		if err != nil || !changed {
+			return err
+		}
+		inst.UpdatedAt = time.Now().UTC()
+		err = o.store.Update(ctx, inst)
+		if errors.Is(err, ErrConflict) && attempt < maxConflicts {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		o.send(ctx, inst, cmd)
+		return nil
+	}
+}
+
+// errStopped is returned for replies received after Stop when the subscriber cannot
+// unsubscribe, subscribers that redeliver pass them to another orchestrator.
+var errStopped = errors.New("saga: orchestrator stopped")
+
+func (o *Orchestrator) onReply(ctx context.Context, msg *pubsub.Message) error {
+	o.mu.Lock()
+	stopped := o.cancel == nil
+	o.mu.Unlock()
+	if stopped {
+		return errStopped
+	}
+	return o.handleReply(ctx, msg)
+}
+
+// handleReply moves the saga forward on a successful action, backward on a failed one.
+// Replies of another step or phase are duplicates or late, they are dropped.
+func (o *Orchestrator) handleReply(ctx context.Context, msg *pubsub.Message) error {
+	id := msg.Header.Get(IDHeader)
+	step, err := strconv.Atoi(msg.Header.Get(StepHeader))
+	if len(id) == 0 || err != nil {
+		o.log.Warnw("msg", "saga reply without saga headers", "subject", msg.Subject)
+		return nil
+	}
+	phase := msg.Header.Get(PhaseHeader)
+	failed := msg.Header.Get(OutcomeHeader) != OutcomeSuccess
+
+	err = o.transition(ctx, id, func(def *Definition, inst *Instance) (bool, *Command, error) {
+		if inst.Step != step || step >= len(def.Steps) {
+			return false, nil, nil
+		}
+		s := def.Steps[step]
+		switch {
+		case inst.Status == StatusRunning && phase == PhaseAction:
+			if failed {
+				inst.Error = fmt.Sprintf("step %s: %s", s.Name, msg.Header.Get(ErrorHeader))
+				cmd, err := o.backward(ctx, def, inst, step-1)
+				return true, cmd, err
+			}
+			if s.OnReply != nil {
+				if err := s.OnReply(ctx, inst, msg); err != nil {
+					// the action was applied, it is compensated too
+					inst.Error = fmt.Sprintf("step %s reply: %v", s.Name, err)
+					cmd, err := o.backward(ctx, def, inst, step)
+					return true, cmd, err
+				}
+			}
+			cmd, err := o.forward(ctx, def, inst, step+1)
+			return true, cmd, err
+		case inst.Status == StatusCompensating && phase == PhaseCompensation:
+			if failed {
+				inst.Error = fmt.Sprintf("compensation of step %s: %s", s.Name, msg.Header.Get(ErrorHeader))
+				o.finish(inst, StatusFailed)
+				return true, nil, nil
+			}
+			cmd, err := o.backward(ctx, def, inst, step-1)
+			return true, cmd, err
+		}
+		return false, nil, nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		o.log.Warnw("msg", "saga reply for an unknown saga", "id", id)
+		return nil
+	}
+	return err
+}
+
+// CheckTimeouts handles one batch of sagas past their deadline and returns its size. A
+// timed out action is compensated with the steps before it, since it may have been
+// applied. A timed out compensation is sent again until the attempts are exhausted.
+func (o *Orchestrator) CheckTimeouts(ctx context.Context) (int, error) {
+	n, _, err := o.checkTimeouts(ctx)
+	return n, err
+}
+
+// checkTimeouts returns the size of the batch and the number of sagas it moved.
+func (o *Orchestrator) checkTimeouts(ctx context.Context) (int, int, error) {
+	now := time.Now().UTC()
+	expired, err := o.store.Expired(ctx, now, defaultBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	moved := 0
+	for _, e := range expired {
+		var changed bool
+		err := o.transition(ctx, e.ID, func(def *Definition, inst *Instance) (bool, *Command, error) {
+			changed = false
+			if inst.Status.Done() || inst.Deadline.After(now) || inst.Step >= len(def.Steps) {
+				return false, nil, nil
+			}
+			changed = true
+			s := def.Steps[inst.Step]
+			if inst.Status == StatusRunning {
+				inst.Error = fmt.Sprintf("step %s timed out", s.Name)
+				cmd, err := o.backward(ctx, def, inst, inst.Step)
+				return true, cmd, err
+			}
+			if inst.Attempts >= o.compensationAttempts {
+				inst.Error = fmt.Sprintf("compensation of step %s timed out", s.Name)
+				o.finish(inst, StatusFailed)
+				return true, nil, nil
+			}
+			cmd, err := o.backward(ctx, def, inst, inst.Step)
+			return true, cmd, err
+		})
+		if err != nil {
+			o.log.Errorw("msg", "saga timeout failed", "id", e.ID, "error", err)
+			continue
+		}
+		if changed {
+			moved++
+		}
+	}
+	return len(expired), moved, nil
+}
+
+func (o *Orchestrator) Start(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.cancel != nil {
+		return nil
+	}
+	if !o.subscribed {
+		if err := o.subscriber.Subscribe(o.replyTopic, o.queueGroup, o.onReply); err != nil {
+			return err
+		}
+		o.subscribed = true
+	}
+	ctx, o.cancel = context.WithCancel(context.Background())
+	o.done = make(chan struct{})
+	go o.run(ctx, o.done)
+	o.log.Infof("[SAGA] orchestrator started on reply topic %s", o.replyTopic)
+	return nil
+}
+
+// Stop unsubscribes from the reply topic and stops the timeout checks, the subscriber
+// is closed by its owner. When the subscriber cannot unsubscribe, replies are refused
+// with an error until Start.
+func (o *Orchestrator) Stop(ctx context.Context) error {
+	o.mu.Lock()
+	cancel, done := o.cancel, o.done
+	o.cancel = nil
+	o.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	err := pubsub.Unsubscribe(o.subscriber, o.replyTopic, o.queueGroup)
+	if err == nil {
+		o.mu.Lock()
+		o.subscribed = false
+		o.mu.Unlock()
+	} else if !errors.Is(err, pubsub.ErrUnsubscribeUnsupported) {
+		o.log.Errorw("msg", "saga reply unsubscribe failed", "topic", o.replyTopic, "error", err)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	o.log.Info("[SAGA] orchestrator stopped")
+	return nil
+}
+
+func (o *Orchestrator) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// keep going while full batches make progress, sagas that cannot time out
+		// (unknown definition, no step left) would otherwise come back every time
+		for {
+			n, moved, err := o.checkTimeouts(ctx)
+			if err != nil && ctx.Err() == nil {
+				o.log.Errorw("msg", "saga timeout check failed", "error", err)
+			}
+			if err != nil || n < defaultBatchSize || moved == 0 {
+				break
+			}
+		}
+	}
+}
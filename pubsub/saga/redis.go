@@ -0,0 +1,189 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+var _ Store = (*RedisStore)(nil)
+
+// createScript stores the saga when its key is free and indexes its deadline.
+var createScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX') == false then
+	return 0
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[3])
+end
+return 1
+`)
+
+// updateScript replaces the saga when the stored version is ARGV[2], the deadline index
+// follows the saga and finished sagas expire after ARGV[5] milliseconds.
+var updateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current == false then
+	return -1
+end
+if cjson.decode(current)['version'] ~= tonumber(ARGV[2]) then
+	return 0
+end
+if tonumber(ARGV[5]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[5])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+else
+	redis.call('ZREM', KEYS[2], ARGV[4])
+end
+return 1
+`)
+
+// RedisOption is redis store option.
+type RedisOption func(*RedisStore)
+
+// WithRetention expires finished sagas after d, by default they are kept.
+func WithRetention(d time.Duration) RedisOption {
+	return func(s *RedisStore) {
+		s.retention = d
+	}
+}
+
+// RedisStore keeps every saga as json in saga:<id> and the deadlines of the sagas
+// waiting for a reply in the sorted set saga:deadlines.
+type RedisStore struct {
+	client    *redisdb.RedisClient
+	owned     bool
+	retention time.Duration
+}
+
+// NewRedisStore connects to redis, the connection is closed by Close.
+func NewRedisStore(c *conf.Redis, opts ...RedisOption) *RedisStore {
+	s := NewRedisStoreWithClient(redisdb.NewRedisClient(c), opts...)
+	s.owned = true
+	return s
+}
+
+// NewRedisStoreWithClient uses a client owned by the caller.
+func NewRedisStoreWithClient(client *redisdb.RedisClient, opts ...RedisOption) *RedisStore {
+	s := &RedisStore{client: client}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.client.KeyBuilder().Key("saga", id)
+}
+
+func (s *RedisStore) deadlines() string {
+	return s.client.KeyBuilder().Key("saga", "deadlines")
+}
+
+func (s *RedisStore) Create(ctx context.Context, inst *Instance) error {
+	b, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	keys := []string{s.key(inst.ID), s.deadlines()}
+	created, err := createScript.Run(ctx, s.client.GetClient(), keys, b, deadlineScore(inst), inst.ID).Int()
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Instance, error) {
+	b, err := s.client.GetClient().Get(ctx, s.key(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	inst := &Instance{}
+	if err := json.Unmarshal(b, inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+func (s *RedisStore) Update(ctx context.Context, inst *Instance) error {
+	next := *inst
+	next.Version++
+	b, err := json.Marshal(&next)
+	if err != nil {
+		return err
+	}
+	var ttl int64
+	if inst.Status.Done() {
+		ttl = s.retention.Milliseconds()
+	}
+	keys := []string{s.key(inst.ID), s.deadlines()}
+	res, err := updateScript.Run(ctx, s.client.GetClient(), keys, b, inst.Version, deadlineScore(inst), inst.ID, ttl).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case -1:
+		return ErrNotFound
+	case 0:
+		return ErrConflict
+	}
+	inst.Version = next.Version
+	return nil
+}
+
+func (s *RedisStore) Expired(ctx context.Context, now time.Time, limit int) ([]*Instance, error) {
+	ids, err := s.client.GetClient().ZRangeByScore(ctx, s.deadlines(), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	expired := make([]*Instance, 0, len(ids))
+	for _, id := range ids {
+		inst, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			// the saga expired, drop its deadline
+			s.client.GetClient().ZRem(ctx, s.deadlines(), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, inst)
+	}
+	return expired, nil
+}
+
+// Close closes the connection when the store opened it.
+func (s *RedisStore) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.client.GetClient().Close()
+}
+
+func deadlineScore(inst *Instance) int64 {
+	if inst.Status.Done() || inst.Deadline.IsZero() {
+		return 0
+	}
+	return inst.Deadline.UnixMilli()
+}
@@ -0,0 +1,185 @@
+// Package saga orchestrates workflows spanning several services. A saga is a list
+// of steps declared in Go, each step sends a command to a participant and waits for
+// its reply. When a step fails or times out the completed steps are undone in reverse
+// order by their compensations.
+//
+// The orchestrator persists the saga state in a Store after every transition and sends
+// the commands through a pubsub.Publisher. Participants answer on the reply topic with
+// Reply or Handle, replies are correlated by the saga id and step headers.
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// Headers of saga commands and replies.
+const (
+	IDHeader      = "saga-id"
+	StepHeader    = "saga-step"
+	PhaseHeader   = "saga-phase"
+	ReplyToHeader = "saga-reply-to"
+	OutcomeHeader = "saga-outcome"
+	ErrorHeader   = "saga-error"
+)
+
+// Phases of a command.
+const (
+	PhaseAction       = "action"
+	PhaseCompensation = "compensation"
+)
+
+// Outcomes of a reply.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+var (
+	ErrNotFound = errors.New("saga: not found")
+	// ErrConflict is returned by Store.Update when the saga was updated concurrently.
+	ErrConflict = errors.New("saga: concurrent update")
+)
+
+// Status is the state of a saga.
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusCompensating Status = "compensating"
+	StatusCompleted    Status = "completed"
+	StatusCompensated  Status = "compensated"
+	// StatusFailed means a compensation failed, the saga needs a manual fix.
+	StatusFailed Status = "failed"
+)
+
+// Done reports whether the saga reached a final status.
+func (s Status) Done() bool {
+	return s == StatusCompleted || s == StatusCompensated || s == StatusFailed
+}
+
+// Instance is the persisted state of a saga. Step is the step waiting for a reply,
+// Data is the json encoded saga data shared by the steps.
+type Instance struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Status    Status          `json:"status"`
+	Step      int             `json:"step"`
+	Attempts  int             `json:"attempts"`
+	Data      json.RawMessage `json:"data"`
+	Error     string          `json:"error,omitempty"`
+	Version   int             `json:"version"`
+	Deadline  time.Time       `json:"deadline"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Decode decodes the saga data into v.
+func (i *Instance) Decode(v interface{}) error {
+	return json.Unmarshal(i.Data, v)
+}
+
+// Encode replaces the saga data with v.
+func (i *Instance) Encode(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	i.Data = data
+	return nil
+}
+
+// Command is sent to a participant, Value is encoded with the codec of Topic.
+type Command struct {
+	Topic string
+	Value interface{}
+}
+
+// Step is a step of a saga. Action and Compensation build the command from the saga
+// data, they may run more than once and must not have side effects. A nil command
+// completes the step without waiting for a reply.
+type Step struct {
+	Name   string
+	Action func(ctx context.Context, inst *Instance) (*Command, error)
+	// OnReply updates the saga data with the reply of a successful action.
+	OnReply func(ctx context.Context, inst *Instance, reply *pubsub.Message) error
+	// Compensation undoes a completed action, nil when there is nothing to undo.
+	Compensation func(ctx context.Context, inst *Instance) (*Command, error)
+	// Timeout overrides the orchestrator step timeout.
+	Timeout time.Duration
+}
+
+// Definition declares a saga.
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+// Send returns an Action or Compensation sending the saga data decoded as T, built by
+// fn, to topic.
+func Send[T any](topic string, fn func(data T) interface{}) func(ctx context.Context, inst *Instance) (*Command, error) {
+	return func(ctx context.Context, inst *Instance) (*Command, error) {
+		var data T
+		if err := inst.Decode(&data); err != nil {
+			return nil, err
+		}
+		return &Command{Topic: topic, Value: fn(data)}, nil
+	}
+}
+
+// Store persists saga instances.
+type Store interface {
+	Create(ctx context.Context, inst *Instance) error
+	Get(ctx context.Context, id string) (*Instance, error)
+	// Update saves inst when the stored version is inst.Version and increments
+	// inst.Version, it returns ErrConflict otherwise.
+	Update(ctx context.Context, inst *Instance) error
+	// Expired returns up to limit sagas waiting for a reply past their deadline.
+	Expired(ctx context.Context, now time.Time, limit int) ([]*Instance, error)
+}
+
+// Reply answers the saga command cmd on its reply topic, a nil err is a success and
+// v, when not nil, the reply value encoded with DefaultCodecs.
+func Reply(ctx context.Context, p pubsub.Publisher, cmd *pubsub.Message, v interface{}, err error) error {
+	replyTo := cmd.Header.Get(ReplyToHeader)
+	if len(replyTo) == 0 {
+		return errors.New("saga: command has no reply topic")
+	}
+	msg := pubsub.NewMessage(nil)
+	if v != nil {
+		var encErr error
+		if msg, encErr = pubsub.DefaultCodecs.Encode(replyTo, v); encErr != nil {
+			return encErr
+		}
+	}
+	for _, key := range []string{IDHeader, StepHeader, PhaseHeader} {
+		msg.Header.Set(key, cmd.Header.Get(key))
+	}
+	msg.Header.Set(OutcomeHeader, OutcomeSuccess)
+	if err != nil {
+		msg.Header.Set(OutcomeHeader, OutcomeFailure)
+		msg.Header.Set(ErrorHeader, err.Error())
+	}
+	return p.Publish(ctx, replyTo, msg)
+}
+
+// Handle returns a participant handler decoding commands as Req and replying with the
+// result of fn. An error of fn is replied as a step failure, the handler only fails
+// when the reply cannot be sent.
+func Handle[Req, Resp any](p pubsub.Publisher, fn func(ctx context.Context, req Req) (Resp, error)) pubsub.Handler {
+	return func(ctx context.Context, msg *pubsub.Message) error {
+		req, err := pubsub.DecodeValue[Req](msg)
+		if err != nil {
+			return Reply(ctx, p, msg, nil, err)
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return Reply(ctx, p, msg, nil, err)
+		}
+		return Reply(ctx, p, msg, resp, nil)
+	}
+}
@@ -0,0 +1,255 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/pubsub/membroker"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	sagas  map[string]Instance
+	checks int // calls to Expired
+}
+
+func (s *memoryStore) Create(ctx context.Context, inst *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sagas[inst.ID] = *inst
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id string) (*Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.sagas[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &inst, nil
+}
+
+func (s *memoryStore) Update(ctx context.Context, inst *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sagas[inst.ID].Version != inst.Version {
+		return ErrConflict
+	}
+	inst.Version++
+	s.sagas[inst.ID] = *inst
+	return nil
+}
+
+func (s *memoryStore) Expired(ctx context.Context, now time.Time, limit int) ([]*Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks++
+	var expired []*Instance
+	for _, inst := range s.sagas {
+		if !inst.Status.Done() && inst.Deadline.Before(now) {
+			inst := inst
+			expired = append(expired, &inst)
+		}
+	}
+	return expired, nil
+}
+
+type order struct {
+	ID        string `json:"id"`
+	Amount    int    `json:"amount"`
+	PaymentID string `json:"payment_id,omitempty"`
+}
+
+type payment struct {
+	PaymentID string `json:"payment_id"`
+}
+
+// eachStore runs test against the memory store and a redis store on miniredis.
+func eachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, &memoryStore{sagas: map[string]Instance{}})
+	})
+	t.Run("redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		test(t, NewRedisStoreWithClient(redisdb.WrapClient(client)))
+	})
+}
+
+func newOrchestrator(t *testing.T, broker *membroker.Broker, store Store, opts ...Option) *Orchestrator {
+	o := NewOrchestrator(store, broker, broker, "saga.replies", opts...)
+	require.Nil(t, o.Register(Definition{
+		Name: "checkout",
+		Steps: []Step{
+			{
+				Name:         "order",
+				Action:       Send("orders.create", func(o order) interface{} { return o }),
+				Compensation: Send("orders.cancel", func(o order) interface{} { return o }),
+			},
+			{
+				Name:   "payment",
+				Action: Send("payments.charge", func(o order) interface{} { return o }),
+				OnReply: func(ctx context.Context, inst *Instance, reply *pubsub.Message) error {
+					var data order
+					if err := inst.Decode(&data); err != nil {
+						return err
+					}
+					p, err := pubsub.DecodeValue[payment](reply)
+					if err != nil {
+						return err
+					}
+					data.PaymentID = p.PaymentID
+					return inst.Encode(data)
+				},
+				Compensation: Send("payments.refund", func(o order) interface{} { return o }),
+			},
+			{
+				Name:   "inventory",
+				Action: Send("inventory.reserve", func(o order) interface{} { return o }),
+			},
+		},
+	}))
+	require.Nil(t, o.Start(context.Background()))
+	t.Cleanup(func() {
+		require.Nil(t, o.Stop(context.Background()))
+	})
+	return o
+}
+
+func participant(t *testing.T, broker *membroker.Broker, topic string, fn func(ctx context.Context, o order) (interface{}, error)) {
+	require.Nil(t, broker.Subscribe(topic, "", Handle(broker, fn)))
+}
+
+func TestOrchestrator_Completed(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		broker := membroker.New(membroker.Sync())
+		o := newOrchestrator(t, broker, store)
+		participant(t, broker, "orders.create", func(ctx context.Context, o order) (interface{}, error) { return nil, nil })
+		participant(t, broker, "payments.charge", func(ctx context.Context, o order) (interface{}, error) {
+			return payment{PaymentID: "pay-" + o.ID}, nil
+		})
+		participant(t, broker, "inventory.reserve", func(ctx context.Context, o order) (interface{}, error) {
+			require.Equal(t, "pay-1", o.PaymentID)
+			return nil, nil
+		})
+
+		inst, err := o.Begin(context.Background(), "checkout", order{ID: "1", Amount: 10})
+		require.Nil(t, err)
+		inst, err = o.Get(context.Background(), inst.ID)
+		require.Nil(t, err)
+		require.Equal(t, StatusCompleted, inst.Status)
+		require.Empty(t, broker.Messages("orders.cancel"))
+
+		// a duplicate reply is dropped
+		replies := broker.Messages("saga.replies")
+		require.Len(t, replies, 3)
+		require.Nil(t, broker.Publish(context.Background(), "saga.replies", replies[0]))
+		dup, err := o.Get(context.Background(), inst.ID)
+		require.Nil(t, err)
+		require.Equal(t, inst.Version, dup.Version)
+	})
+}
+
+func TestOrchestrator_Compensated(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		broker := membroker.New(membroker.Sync())
+		o := newOrchestrator(t, broker, store)
+		participant(t, broker, "orders.*", func(ctx context.Context, o order) (interface{}, error) { return nil, nil })
+		participant(t, broker, "payments.charge", func(ctx context.Context, o order) (interface{}, error) { return payment{PaymentID: "p"}, nil })
+		participant(t, broker, "payments.refund", func(ctx context.Context, o order) (interface{}, error) { return nil, nil })
+		participant(t, broker, "inventory.reserve", func(ctx context.Context, o order) (interface{}, error) {
+			return nil, errors.New("out of stock")
+		})
+
+		inst, err := o.Begin(context.Background(), "checkout", order{ID: "1"})
+		require.Nil(t, err)
+		inst, err = o.Get(context.Background(), inst.ID)
+		require.Nil(t, err)
+		require.Equal(t, StatusCompensated, inst.Status)
+		require.Equal(t, "step inventory: out of stock", inst.Error)
+		require.Len(t, broker.Messages("payments.refund"), 1)
+		require.Len(t, broker.Messages("orders.cancel"), 1)
+	})
+}
+
+func TestOrchestrator_Timeout(t *testing.T) {
+	eachStore(t, func(t *testing.T, store Store) {
+		broker := membroker.New(membroker.Sync())
+		o := newOrchestrator(t, broker, store, WithStepTimeout(time.Millisecond), WithCompensationAttempts(2))
+		participant(t, broker, "orders.create", func(ctx context.Context, o order) (interface{}, error) { return nil, nil })
+		// payments and order cancellations never reply
+
+		inst, err := o.Begin(context.Background(), "checkout", order{ID: "1"})
+		require.Nil(t, err)
+
+		status := func() Status {
+			time.Sleep(5 * time.Millisecond)
+			_, err := o.CheckTimeouts(context.Background())
+			require.Nil(t, err)
+			inst, err := o.Get(context.Background(), inst.ID)
+			require.Nil(t, err)
+			return inst.Status
+		}
+		// the timed out charge may have been applied, it is refunded
+		require.Equal(t, StatusCompensating, status())
+		require.Len(t, broker.Messages("payments.refund"), 1)
+		require.Equal(t, StatusCompensating, status())
+		require.Len(t, broker.Messages("payments.refund"), 2)
+		require.Equal(t, StatusFailed, status())
+	})
+}
+
+func TestOrchestrator_StuckSagasWaitForTheNextTick(t *testing.T) {
+	store := &memoryStore{sagas: map[string]Instance{}}
+	// a full batch of sagas whose definition is not registered, none can time out
+	for i := 0; i < defaultBatchSize; i++ {
+		id := strconv.Itoa(i)
+		store.sagas[id] = Instance{ID: id, Name: "removed", Status: StatusRunning, Deadline: time.Now().Add(-time.Minute)}
+	}
+	broker := membroker.New(membroker.Sync())
+	o := NewOrchestrator(store, broker, broker, "saga.replies", WithInterval(10*time.Millisecond), WithLogger(log.NewStdLogger(io.Discard)))
+	require.Nil(t, o.Start(context.Background()))
+	time.Sleep(55 * time.Millisecond)
+	require.Nil(t, o.Stop(context.Background()))
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.LessOrEqual(t, store.checks, 6, "one check per tick")
+}
+
+func TestOrchestrator_StopUnsubscribes(t *testing.T) {
+	broker := membroker.New(membroker.Sync())
+	o := newOrchestrator(t, broker, &memoryStore{sagas: map[string]Instance{}})
+	participant(t, broker, "orders.create", func(ctx context.Context, o order) (interface{}, error) { return nil, nil })
+
+	require.Nil(t, o.Stop(context.Background()))
+	inst, err := o.Begin(context.Background(), "checkout", order{ID: "1"})
+	require.Nil(t, err)
+	inst, err = o.Get(context.Background(), inst.ID)
+	require.Nil(t, err)
+	require.Equal(t, 0, inst.Step, "the reply of the order step is not handled once stopped")
+	require.Len(t, broker.Messages("saga.replies"), 1)
+	require.Empty(t, broker.Errors())
+
+	// started again, the reply topic is subscribed once
+	require.Nil(t, o.Start(context.Background()))
+	require.Nil(t, broker.Publish(context.Background(), "saga.replies", broker.Messages("saga.replies")[0]))
+	inst, err = o.Get(context.Background(), inst.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, inst.Step)
+	require.Equal(t, 1, inst.Version)
+}
@@ -0,0 +1,163 @@
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/sqldb"
+)
+
+var _ Store = (*SQLStore)(nil)
+
+const defaultTable = "sagas"
+
+// SQLOption is sql store option.
+type SQLOption func(*SQLStore)
+
+// WithTable sets the saga table name, default is sagas.
+func WithTable(table string) SQLOption {
+	return func(s *SQLStore) {
+		s.table = table
+	}
+}
+
+// SQLStore keeps sagas in a table with the following columns (PostgreSQL shown):
+//
+//	CREATE TABLE sagas (
+//		id         VARCHAR(64) PRIMARY KEY,
+//		name       VARCHAR(255) NOT NULL,
+//		status     VARCHAR(32) NOT NULL,
+//		step       INT NOT NULL,
+//		attempts   INT NOT NULL,
+//		data       TEXT NOT NULL,
+//		error      TEXT NOT NULL,
+//		version    INT NOT NULL,
+//		deadline   TIMESTAMP NULL,
+//		created_at TIMESTAMP NOT NULL,
+//		updated_at TIMESTAMP NOT NULL
+//	);
+//	CREATE INDEX sagas_deadline ON sagas (deadline) WHERE deadline IS NOT NULL;
+type SQLStore struct {
+	db       *sql.DB
+	postgres bool
+	table    string
+}
+
+// NewSQLStore opens the configured database.
+func NewSQLStore(c *conf.Database, opts ...SQLOption) (*SQLStore, error) {
+	db, err := sqldb.Open(c)
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLStoreWithDB(db, c.Driver, opts...), nil
+}
+
+// NewSQLStoreWithDB uses a database handle owned by the caller, driver selects the placeholder style.
+func NewSQLStoreWithDB(db *sql.DB, driver string, opts ...SQLOption) *SQLStore {
+	s := &SQLStore{
+		db:       db,
+		postgres: sqldb.IsPostgres(driver),
+		table:    defaultTable,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *SQLStore) rebind(query string) string {
+	if !s.postgres {
+		return query
+	}
+	return sqldb.Rebind(query)
+}
+
+const columns = "id, name, status, step, attempts, data, error, version, deadline, created_at, updated_at"
+
+func (s *SQLStore) Create(ctx context.Context, inst *Instance) error {
+	query := "INSERT INTO " + s.table + " (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := s.db.ExecContext(ctx, s.rebind(query), inst.ID, inst.Name, string(inst.Status), inst.Step, inst.Attempts,
+		string(inst.Data), inst.Error, inst.Version, deadline(inst), inst.CreatedAt, inst.UpdatedAt)
+	return err
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (*Instance, error) {
+	query := "SELECT " + columns + " FROM " + s.table + " WHERE id = ?"
+	inst, err := scan(s.db.QueryRowContext(ctx, s.rebind(query), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return inst, err
+}
+
+func (s *SQLStore) Update(ctx context.Context, inst *Instance) error {
+	query := "UPDATE " + s.table + " SET status = ?, step = ?, attempts = ?, data = ?, error = ?, version = ?, deadline = ?, updated_at = ?" +
+		" WHERE id = ? AND version = ?"
+	res, err := s.db.ExecContext(ctx, s.rebind(query), string(inst.Status), inst.Step, inst.Attempts, string(inst.Data),
+		inst.Error, inst.Version+1, deadline(inst), inst.UpdatedAt, inst.ID, inst.Version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := s.Get(ctx, inst.ID); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	inst.Version++
+	return nil
+}
+
+func (s *SQLStore) Expired(ctx context.Context, now time.Time, limit int) ([]*Instance, error) {
+	query := "SELECT " + columns + " FROM " + s.table + " WHERE deadline IS NOT NULL AND deadline <= ? ORDER BY deadline LIMIT ?"
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var expired []*Instance
+	for rows.Next() {
+		inst, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, inst)
+	}
+	return expired, rows.Err()
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner) (*Instance, error) {
+	inst := &Instance{}
+	var status, data string
+	var dl sql.NullTime
+	err := row.Scan(&inst.ID, &inst.Name, &status, &inst.Step, &inst.Attempts, &data, &inst.Error,
+		&inst.Version, &dl, &inst.CreatedAt, &inst.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	inst.Status = Status(status)
+	inst.Data = []byte(data)
+	if dl.Valid {
+		inst.Deadline = dl.Time
+	}
+	return inst, nil
+}
+
+func deadline(inst *Instance) sql.NullTime {
+	if inst.Status.Done() || inst.Deadline.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: inst.Deadline, Valid: true}
+}
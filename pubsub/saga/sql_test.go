@@ -0,0 +1,66 @@
+package saga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+var sqlColumns = []string{"id", "name", "status", "step", "attempts", "data", "error", "version", "deadline", "created_at", "updated_at"}
+
+func TestSQLStore_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	store := NewSQLStoreWithDB(db, "pgx")
+	now := time.Now().UTC()
+	inst := &Instance{ID: "s1", Name: "checkout", Status: StatusRunning, Step: 1, Attempts: 1, Data: []byte("{}"),
+		Version: 2, Deadline: now.Add(time.Minute), UpdatedAt: now}
+	update := `UPDATE sagas SET .* WHERE id = \$9 AND version = \$10`
+	get := `SELECT .* FROM sagas WHERE id = \$1`
+
+	mock.ExpectExec(update).
+		WithArgs("running", 1, 1, "{}", "", 3, inst.Deadline, now, "s1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.Nil(t, store.Update(context.Background(), inst))
+	require.Equal(t, 3, inst.Version)
+
+	// the row exists with another version
+	mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(get).WithArgs("s1").WillReturnRows(sqlmock.NewRows(sqlColumns).
+		AddRow("s1", "checkout", "running", 1, 1, "{}", "", 4, nil, now, now))
+	require.ErrorIs(t, store.Update(context.Background(), inst), ErrConflict)
+	require.Equal(t, 3, inst.Version)
+
+	mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(get).WithArgs("s1").WillReturnRows(sqlmock.NewRows(sqlColumns))
+	require.ErrorIs(t, store.Update(context.Background(), inst), ErrNotFound)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLStore_Expired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	store := NewSQLStoreWithDB(db, "pgx")
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`SELECT .* FROM sagas WHERE deadline IS NOT NULL AND deadline <= \$1 ORDER BY deadline LIMIT \$2`).
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows(sqlColumns).
+			AddRow("s1", "checkout", "running", 0, 1, `{"id":"1"}`, "", 1, now.Add(-time.Second), now, now).
+			AddRow("s2", "checkout", "compensating", 1, 2, "{}", "step payment timed out", 3, now, now, now))
+	expired, err := store.Expired(context.Background(), now, 10)
+	require.Nil(t, err)
+	require.Len(t, expired, 2)
+	require.Equal(t, "s1", expired[0].ID)
+	require.Equal(t, StatusRunning, expired[0].Status)
+	require.JSONEq(t, `{"id":"1"}`, string(expired[0].Data))
+	require.Equal(t, now.Add(-time.Second), expired[0].Deadline)
+	require.Equal(t, StatusCompensating, expired[1].Status)
+	require.Equal(t, 2, expired[1].Attempts)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
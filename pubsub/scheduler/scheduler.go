@@ -0,0 +1,339 @@
+// Package scheduler delivers messages at a later time. Messages are kept in redis, in
+// a sorted set keyed by due time, and published by pollers which may run in every pod.
+//
+// Delivery is at least once: a poller leases the due messages atomically, publishes
+// them and then acknowledges them. Messages of a poller which died before the ack are
+// published again once their lease expires, consumers dedupe with the IDHeader.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+// IDHeader carries the schedule id on published messages.
+const IDHeader = "schedule-id"
+
+const (
+	defaultQueue          = "default"
+	defaultInterval       = time.Second
+	defaultBatchSize      = 100
+	defaultLease          = 30 * time.Second
+	defaultPublishTimeout = 5 * time.Second
+)
+
+var _ transport.Server = (*Scheduler)(nil)
+
+// scheduleScript stores the message and its due time, an existing id is rescheduled.
+var scheduleScript = redis.NewScript(`
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[3])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// cancelScript removes a message which is not leased.
+var cancelScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+// leaseScript moves the messages of expired leases back to the due set, then leases
+// up to ARGV[3] due messages until ARGV[2] and returns their ids and payloads.
+var leaseScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	if redis.call('ZSCORE', KEYS[1], id) == false then
+		redis.call('ZADD', KEYS[1], ARGV[1], id)
+	end
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local result = {}
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		redis.call('ZADD', KEYS[2], ARGV[2], id)
+		table.insert(result, id)
+		table.insert(result, payload)
+	end
+end
+return result
+`)
+
+// ackScript forgets a published message unless it was rescheduled meanwhile.
+var ackScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[2], ARGV[1]) == 1 and redis.call('ZSCORE', KEYS[1], ARGV[1]) == false then
+	redis.call('HDEL', KEYS[3], ARGV[1])
+end
+return 1
+`)
+
+// Option is scheduler option.
+type Option func(*Scheduler)
+
+// WithQueue isolates the messages of a scheduler, default is default.
+func WithQueue(queue string) Option {
+	return func(s *Scheduler) {
+		s.queue = queue
+	}
+}
+
+// WithInterval sets how often due messages are polled.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = interval
+	}
+}
+
+// WithBatchSize bounds the number of messages leased per poll.
+func WithBatchSize(n int) Option {
+	return func(s *Scheduler) {
+		s.batchSize = n
+	}
+}
+
+// WithLease sets how long a poller owns the messages it leased, they are published
+// again by any poller once it expired. It must exceed the publish time of a batch.
+func WithLease(lease time.Duration) Option {
+	return func(s *Scheduler) {
+		s.lease = lease
+	}
+}
+
+// WithPublishTimeout bounds a single publish.
+func WithPublishTimeout(timeout time.Duration) Option {
+	return func(s *Scheduler) {
+		s.publishTimeout = timeout
+	}
+}
+
+// WithLogger with scheduler logger.
+func WithLogger(logger log.Logger) Option {
+	return func(s *Scheduler) {
+		s.log = log.NewHelper(logger)
+	}
+}
+
+type entry struct {
+	Topic  string        `json:"topic"`
+	Header pubsub.Header `json:"header,omitempty"`
+	Data   []byte        `json:"data"`
+}
+
+// Scheduler stores delayed messages and publishes them when they are due. It is a
+// kratos transport.Server, Start runs the poller.
+type Scheduler struct {
+	client         *redisdb.RedisClient
+	owned          bool
+	publisher      pubsub.Publisher
+	log            *log.Helper
+	queue          string
+	interval       time.Duration
+	batchSize      int
+	lease          time.Duration
+	publishTimeout time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New connects to redis, the connection is closed by Close.
+func New(c *conf.Redis, publisher pubsub.Publisher, opts ...Option) *Scheduler {
+	s := NewWithClient(redisdb.NewRedisClient(c), publisher, opts...)
+	s.owned = true
+	return s
+}
+
+// NewWithClient uses a client owned by the caller.
+func NewWithClient(client *redisdb.RedisClient, publisher pubsub.Publisher, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		client:         client,
+		publisher:      publisher,
+		log:            log.NewHelper(log.GetLogger()),
+		queue:          defaultQueue,
+		interval:       defaultInterval,
+		batchSize:      defaultBatchSize,
+		lease:          defaultLease,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// keys returns the due set, the lease set and the message hash of the queue, they
+// share a hash tag to live on the same cluster slot.
+func (s *Scheduler) keys() []string {
+	keys := s.client.KeyBuilder()
+	return []string{
+		keys.Tagged("scheduler:"+s.queue, "due"),
+		keys.Tagged("scheduler:"+s.queue, "leased"),
+		keys.Tagged("scheduler:"+s.queue, "messages"),
+	}
+}
+
+// Schedule publishes msg on topic at the given time and returns its id. An empty id is
+// generated, scheduling an existing id replaces its message and time.
+func (s *Scheduler) Schedule(ctx context.Context, id, topic string, msg *pubsub.Message, at time.Time) (string, error) {
+	if len(id) == 0 {
+		id = nuid.Next()
+	}
+	payload, err := json.Marshal(entry{Topic: topic, Header: msg.Header, Data: msg.Data})
+	if err != nil {
+		return "", err
+	}
+	if err := scheduleScript.Run(ctx, s.client.GetClient(), s.keys(), id, at.UnixMilli(), payload).Err(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ScheduleIn publishes msg on topic after delay.
+func (s *Scheduler) ScheduleIn(ctx context.Context, id, topic string, msg *pubsub.Message, delay time.Duration) (string, error) {
+	return s.Schedule(ctx, id, topic, msg, time.Now().Add(delay))
+}
+
+// Cancel removes a scheduled message, it reports false when the id is unknown or the
+// message is being published.
+func (s *Scheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	n, err := cancelScript.Run(ctx, s.client.GetClient(), s.keys(), id).Int()
+	return n == 1, err
+}
+
+// PollOnce publishes one batch of due messages and returns its size. Messages which
+// fail to publish stay leased and are retried once the lease expired.
+func (s *Scheduler) PollOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	keys := s.keys()
+	res, err := leaseScript.Run(ctx, s.client.GetClient(), keys,
+		now.UnixMilli(), now.Add(s.lease).UnixMilli(), s.batchSize).StringSlice()
+	if err != nil {
+		return 0, err
+	}
+	n := len(res) / 2
+	for i := 0; i < n; i++ {
+		id, payload := res[2*i], res[2*i+1]
+		if err := s.publish(ctx, id, payload); err != nil {
+			s.log.Errorw("msg", "scheduled message publish failed", "queue", s.queue, "id", id, "error", err)
+			continue
+		}
+		if err := ackScript.Run(ctx, s.client.GetClient(), keys, id).Err(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (s *Scheduler) publish(ctx context.Context, id, payload string) error {
+	var e entry
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		return err
+	}
+	msg := &pubsub.Message{Header: e.Header, Data: e.Data}
+	if msg.Header == nil {
+		msg.Header = pubsub.Header{}
+	}
+	msg.Header.Set(IDHeader, id)
+	ctx, cancel := context.WithTimeout(ctx, s.publishTimeout)
+	defer cancel()
+	return s.publisher.Publish(ctx, e.Topic, msg)
+}
+
+// Pending returns the number of scheduled messages, leased ones included.
+func (s *Scheduler) Pending(ctx context.Context) (int64, error) {
+	return s.client.GetClient().HLen(ctx, s.keys()[2]).Result()
+}
+
+// Due returns the time id is due, false when it is not scheduled or being published.
+func (s *Scheduler) Due(ctx context.Context, id string) (time.Time, bool, error) {
+	score, err := s.client.GetClient().ZScore(ctx, s.keys()[0], id).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(int64(score)), true, nil
+}
+
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return nil
+	}
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+	s.log.Infof("[SCHEDULER] poller started on queue %s", s.queue)
+	return nil
+}
+
+// Stop stops polling and waits for the running batch.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.log.Info("[SCHEDULER] poller stopped")
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		// keep polling while full batches are due
+		for {
+			n, err := s.PollOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.log.Errorw("msg", "scheduler poll failed", "queue", s.queue, "error", err)
+			}
+			if err != nil || n < s.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the connection when the scheduler opened it.
+func (s *Scheduler) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.client.GetClient().Close()
+}
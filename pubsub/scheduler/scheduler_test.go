@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/pubsub/membroker"
+)
+
+type failingPublisher struct {
+	pubsub.Publisher
+}
+
+func (failingPublisher) Publish(context.Context, string, *pubsub.Message) error {
+	return errors.New("broker unavailable")
+}
+
+func newClient(t *testing.T) *redisdb.RedisClient {
+	mr := miniredis.RunT(t)
+	return redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+}
+
+func TestScheduler_PublishDue(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	broker := membroker.New(membroker.Sync())
+	s := NewWithClient(client, broker)
+
+	msg := pubsub.NewMessage([]byte("remind"))
+	msg.Header.Set("user", "1")
+	due, err := s.Schedule(ctx, "", "reminders", msg, time.Now().Add(-time.Second))
+	require.Nil(t, err)
+	_, err = s.ScheduleIn(ctx, "later", "reminders", pubsub.NewMessage([]byte("later")), time.Hour)
+	require.Nil(t, err)
+	_, err = s.ScheduleIn(ctx, "cancelled", "reminders", pubsub.NewMessage(nil), 0)
+	require.Nil(t, err)
+
+	ok, err := s.Cancel(ctx, "cancelled")
+	require.Nil(t, err)
+	require.True(t, ok)
+	ok, err = s.Cancel(ctx, "cancelled")
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	n, err := s.PollOnce(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	published := broker.Messages("reminders")
+	require.Len(t, published, 1)
+	require.Equal(t, "remind", string(published[0].Data))
+	require.Equal(t, "1", published[0].Header.Get("user"))
+	require.Equal(t, due, published[0].Header.Get(IDHeader))
+
+	pending, err := s.Pending(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), pending)
+	_, scheduled, err := s.Due(ctx, "later")
+	require.Nil(t, err)
+	require.True(t, scheduled)
+}
+
+func TestScheduler_RetryExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	// the first pod fails to publish, its lease expires and another pod publishes
+	failing := NewWithClient(client, failingPublisher{}, WithLease(time.Millisecond))
+	_, err := failing.ScheduleIn(ctx, "reminder", "reminders", pubsub.NewMessage([]byte("remind")), 0)
+	require.Nil(t, err)
+	n, err := failing.PollOnce(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+
+	ok, err := failing.Cancel(ctx, "reminder")
+	require.Nil(t, err)
+	require.False(t, ok, "a leased message cannot be cancelled")
+
+	broker := membroker.New(membroker.Sync())
+	s := NewWithClient(client, broker)
+	time.Sleep(5 * time.Millisecond)
+	n, err = s.PollOnce(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, broker.Messages("reminders"), 1)
+
+	pending, err := s.Pending(ctx)
+	require.Nil(t, err)
+	require.Zero(t, pending)
+}
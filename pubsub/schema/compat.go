@@ -0,0 +1,287 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Compatibility is the rule a new schema version must follow.
+type Compatibility string
+
+const (
+	// CompatibilityNone accepts any change.
+	CompatibilityNone Compatibility = "none"
+	// CompatibilityBackward means consumers using the new schema read messages of the previous one.
+	CompatibilityBackward Compatibility = "backward"
+	// CompatibilityForward means consumers using the previous schema read messages of the new one.
+	CompatibilityForward Compatibility = "forward"
+	// CompatibilityFull is both backward and forward.
+	CompatibilityFull Compatibility = "full"
+)
+
+// IncompatibleError lists the changes breaking the compatibility.
+type IncompatibleError struct {
+	Subject  string
+	Mode     Compatibility
+	Problems []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schema: %s is not %s compatible: %s", e.Subject, e.Mode, strings.Join(e.Problems, "; "))
+}
+
+// CheckCompatibility reports whether next can follow prev under mode, it returns an
+// *IncompatibleError listing the breaking changes. It needs no registry, so it can
+// run in unit tests against the schema files of a repository.
+func CheckCompatibility(prev, next *Schema, mode Compatibility) error {
+	if mode == CompatibilityNone || len(mode) == 0 {
+		return nil
+	}
+	if prev.Format != next.Format {
+		return &IncompatibleError{Subject: next.Subject, Mode: mode, Problems: []string{"format changed from " + string(prev.Format) + " to " + string(next.Format)}}
+	}
+
+	var readable func(reader, writer *Schema) ([]string, error)
+	switch next.Format {
+	case FormatJSON:
+		readable = readableJSON
+	case FormatProtobuf:
+		readable = readableProto
+	default:
+		return fmt.Errorf("schema: unknown format %q", next.Format)
+	}
+
+	var problems []string
+	if mode == CompatibilityBackward || mode == CompatibilityFull {
+		p, err := readable(next, prev)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+	}
+	if mode == CompatibilityForward || mode == CompatibilityFull {
+		p, err := readable(prev, next)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+	}
+	if len(problems) > 0 {
+		return &IncompatibleError{Subject: next.Subject, Mode: mode, Problems: problems}
+	}
+	return nil
+}
+
+// readableJSON reports why documents valid for writer may be invalid for reader.
+// It covers object properties, required lists, types, enums and array items.
+func readableJSON(reader, writer *Schema) ([]string, error) {
+	var r, w map[string]interface{}
+	if err := json.Unmarshal(reader.Definition, &r); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(writer.Definition, &w); err != nil {
+		return nil, err
+	}
+	return readableJSONNode("$", r, w), nil
+}
+
+func readableJSONNode(path string, r, w map[string]interface{}) []string {
+	var problems []string
+	if rt, wt := jsonTypes(r), jsonTypes(w); len(rt) > 0 {
+		if len(wt) == 0 {
+			problems = append(problems, path+": type is restricted to "+strings.Join(rt, ","))
+		}
+		for _, t := range wt {
+			if !containsType(rt, t) {
+				problems = append(problems, path+": type "+t+" is not accepted")
+			}
+		}
+	}
+	if re, ok := r["enum"].([]interface{}); ok {
+		we, _ := w["enum"].([]interface{})
+		if we == nil {
+			problems = append(problems, path+": enum was added")
+		}
+		for _, v := range we {
+			if !containsValue(re, v) {
+				problems = append(problems, fmt.Sprintf("%s: enum value %v was removed", path, v))
+			}
+		}
+	}
+
+	rp, _ := r["properties"].(map[string]interface{})
+	wp, _ := w["properties"].(map[string]interface{})
+	wr := stringSet(w["required"])
+	for _, name := range sortedKeys(stringSet(r["required"])) {
+		if wr[name] {
+			continue
+		}
+		if prop, ok := rp[name].(map[string]interface{}); ok {
+			if _, hasDefault := prop["default"]; hasDefault {
+				continue
+			}
+		}
+		problems = append(problems, path+"."+name+": property is required")
+	}
+	if additional, ok := r["additionalProperties"].(bool); ok && !additional {
+		for _, name := range sortedKeys(toSet(wp)) {
+			if _, ok := rp[name]; !ok {
+				problems = append(problems, path+"."+name+": property is not allowed")
+			}
+		}
+	}
+	for _, name := range sortedKeys(toSet(rp)) {
+		rs, _ := rp[name].(map[string]interface{})
+		ws, _ := wp[name].(map[string]interface{})
+		if rs != nil && ws != nil {
+			problems = append(problems, readableJSONNode(path+"."+name, rs, ws)...)
+		}
+	}
+	if ri, ok := r["items"].(map[string]interface{}); ok {
+		if wi, ok := w["items"].(map[string]interface{}); ok {
+			problems = append(problems, readableJSONNode(path+"[]", ri, wi)...)
+		}
+	}
+	return problems
+}
+
+func jsonTypes(s map[string]interface{}) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func containsType(types []string, t string) bool {
+	for _, v := range types {
+		// every integer is a number
+		if v == t || (v == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, x := range values {
+		if fmt.Sprint(x) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringSet(v interface{}) map[string]bool {
+	set := map[string]bool{}
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func toSet(m map[string]interface{}) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readableProto reports the fields whose wire encoding differs between the messages
+// and the proto2 required fields of reader missing in writer.
+func readableProto(reader, writer *Schema) ([]string, error) {
+	r, err := messageDescriptor(reader)
+	if err != nil {
+		return nil, err
+	}
+	w, err := messageDescriptor(writer)
+	if err != nil {
+		return nil, err
+	}
+	return readableMessage(string(r.Name()), r, w, map[protoreflect.FullName]bool{}), nil
+}
+
+func readableMessage(path string, r, w protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) []string {
+	if seen[r.FullName()] {
+		return nil
+	}
+	seen[r.FullName()] = true
+
+	var problems []string
+	fields := r.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		rf := fields.Get(i)
+		wf := w.Fields().ByNumber(rf.Number())
+		name := fmt.Sprintf("%s.%s(%d)", path, rf.Name(), rf.Number())
+		if wf == nil {
+			if rf.Cardinality() == protoreflect.Required {
+				problems = append(problems, name+": required field is missing")
+			}
+			continue
+		}
+		if rf.IsList() != wf.IsList() || rf.IsMap() != wf.IsMap() {
+			problems = append(problems, name+": cardinality changed")
+			continue
+		}
+		if wireType(rf.Kind()) != wireType(wf.Kind()) || wireGroup(rf.Kind()) != wireGroup(wf.Kind()) {
+			problems = append(problems, fmt.Sprintf("%s: type changed from %s to %s", name, wf.Kind(), rf.Kind()))
+			continue
+		}
+		if rf.Message() != nil && wf.Message() != nil {
+			problems = append(problems, readableMessage(name, rf.Message(), wf.Message(), seen)...)
+		}
+	}
+	return problems
+}
+
+func wireType(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return "i32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return "i64"
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind:
+		return "len"
+	case protoreflect.GroupKind:
+		return "group"
+	}
+	return "varint"
+}
+
+// wireGroup separates kinds sharing a wire type but decoded differently.
+func wireGroup(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "float"
+	case protoreflect.MessageKind:
+		return "message"
+	}
+	return ""
+}
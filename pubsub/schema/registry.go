@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/uerror"
+)
+
+// VersionHeader carries the schema version a message was published with.
+const VersionHeader = "schema-version"
+
+// Option is registry option.
+type Option func(*Registry)
+
+// WithCompatibility sets the rule checked when registering a new version, default backward.
+func WithCompatibility(mode Compatibility) Option {
+	return func(r *Registry) {
+		r.mode = mode
+	}
+}
+
+// Registry registers schema versions in a store and validates messages against them.
+type Registry struct {
+	store Store
+	mode  Compatibility
+
+	mu         sync.RWMutex
+	validators map[string]validator // compiled schemas per subject and version
+}
+
+func NewRegistry(store Store, opts ...Option) *Registry {
+	r := &Registry{
+		store:      store,
+		mode:       CompatibilityBackward,
+		validators: map[string]validator{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register stores s as a new version of its subject, a zero version is the latest
+// version plus one. It fails with an *IncompatibleError when s breaks the
+// compatibility with the latest version and with ErrVersionExists when the
+// version was registered concurrently.
+func (r *Registry) Register(ctx context.Context, s *Schema) error {
+	if len(s.Subject) == 0 {
+		return errors.New("schema: subject is required")
+	}
+	latest, err := r.Latest(ctx, s.Subject)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if latest != nil {
+		if s.Version == 0 {
+			s.Version = latest.Version + 1
+		}
+		if s.Version <= latest.Version {
+			return fmt.Errorf("schema: version %d of %s is not after %d", s.Version, s.Subject, latest.Version)
+		}
+		if err := CheckCompatibility(latest, s, r.mode); err != nil {
+			return err
+		}
+	} else if s.Version == 0 {
+		s.Version = 1
+	}
+	v, err := compile(s)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Put(ctx, s); err != nil {
+		if errors.Is(err, ErrVersionExists) {
+			return fmt.Errorf("%w: version %d of %s", err, s.Version, s.Subject)
+		}
+		return err
+	}
+	r.mu.Lock()
+	r.validators[cacheKey(s.Subject, s.Version)] = v
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns a version of subject.
+func (r *Registry) Get(ctx context.Context, subject string, version int) (*Schema, error) {
+	return r.store.Get(ctx, subject, version)
+}
+
+// Latest returns the latest version of subject or ErrNotFound.
+func (r *Registry) Latest(ctx context.Context, subject string) (*Schema, error) {
+	versions, err := r.store.Versions(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return r.store.Get(ctx, subject, versions[len(versions)-1])
+}
+
+// Validate checks msg against the version in its VersionHeader, or the latest version
+// of subject when the header is missing. Messages of subjects without schema are valid.
+// An invalid message is rejected with a bad request error, which consumers treat as terminal.
+func (r *Registry) Validate(ctx context.Context, subject string, msg *pubsub.Message) error {
+	_, err := r.validate(ctx, subject, msg)
+	return err
+}
+
+// validate returns the version msg was checked against, 0 when subject has no schema.
+func (r *Registry) validate(ctx context.Context, subject string, msg *pubsub.Message) (int, error) {
+	version := 0
+	if h := msg.Header.Get(VersionHeader); len(h) > 0 {
+		v, err := strconv.Atoi(h)
+		if err != nil {
+			return 0, uerror.BadRequestError("invalid schema version " + h)
+		}
+		version = v
+	}
+	v, version, err := r.validator(ctx, subject, version)
+	if errors.Is(err, ErrNotFound) {
+		if version > 0 {
+			return 0, uerror.BadRequestError(fmt.Sprintf("unknown schema version %d of %s", version, subject))
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := v.validate(msg.Header.Get(pubsub.ContentTypeHeader), msg.Data); err != nil {
+		return 0, uerror.BadRequestError(fmt.Sprintf("%s does not match schema version %d: %v", subject, version, err))
+	}
+	return version, nil
+}
+
+func (r *Registry) validator(ctx context.Context, subject string, version int) (validator, int, error) {
+	if version == 0 {
+		versions, err := r.store.Versions(ctx, subject)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(versions) == 0 {
+			return nil, 0, ErrNotFound
+		}
+		version = versions[len(versions)-1]
+	}
+	key := cacheKey(subject, version)
+	r.mu.RLock()
+	v, ok := r.validators[key]
+	r.mu.RUnlock()
+	if ok {
+		return v, version, nil
+	}
+	s, err := r.store.Get(ctx, subject, version)
+	if err != nil {
+		return nil, version, err
+	}
+	if v, err = compile(s); err != nil {
+		return nil, version, err
+	}
+	r.mu.Lock()
+	r.validators[key] = v
+	r.mu.Unlock()
+	return v, version, nil
+}
+
+func cacheKey(subject string, version int) string {
+	return subject + "@" + strconv.Itoa(version)
+}
+
+// Middleware validates messages on the publish and consume paths, the subject is the
+// transport operation. On publish the version checked against is set in the VersionHeader.
+func Middleware(r *Registry) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			msg, ok := req.(*pubsub.Message)
+			if !ok {
+				return handler(ctx, req)
+			}
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				if _, ok := tr.(*pubsub.Transport); ok {
+					version, err := r.validate(ctx, tr.Operation(), msg)
+					if err != nil {
+						return nil, err
+					}
+					if version > 0 {
+						msg.Header.Set(VersionHeader, strconv.Itoa(version))
+					}
+					return handler(ctx, req)
+				}
+			}
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				if err := r.Validate(ctx, tr.Operation(), msg); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package schema is a local registry of message schemas keyed by subject and
+// version. Schemas are JSON Schema documents or protobuf descriptor sets, they
+// are stored in files or redis and used to validate messages when they are
+// published and consumed.
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+// Format is the schema language.
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatProtobuf Format = "protobuf"
+)
+
+var (
+	ErrNotFound = errors.New("schema: not found")
+	// ErrVersionExists is returned by Store.Put and Registry.Register when the version
+	// is already registered, stored versions are never overwritten.
+	ErrVersionExists = errors.New("schema: version already registered")
+	// ErrInvalidSubject is returned for subjects that can not be used as a file name.
+	ErrInvalidSubject = errors.New("schema: invalid subject")
+)
+
+// Schema is a version of the schema of a subject. Definition is the JSON Schema
+// document, or for protobuf a serialized FileDescriptorSet and Message the full
+// name of the message in it.
+type Schema struct {
+	Subject    string `json:"subject"`
+	Version    int    `json:"version"`
+	Format     Format `json:"format"`
+	Message    string `json:"message,omitempty"`
+	Definition []byte `json:"definition"`
+}
+
+// Store persists schemas.
+type Store interface {
+	Get(ctx context.Context, subject string, version int) (*Schema, error)
+	// Versions returns the versions of subject in ascending order.
+	Versions(ctx context.Context, subject string) ([]int, error)
+	// Put stores a new version, it fails with ErrVersionExists when the version exists.
+	Put(ctx context.Context, s *Schema) error
+}
+
+// FileStore keeps every schema version in <dir>/<subject>/<version>.json, subjects
+// containing path separators or .. are rejected with ErrInvalidSubject.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) subjectDir(subject string) (string, error) {
+	if len(subject) == 0 || subject == "." || strings.Contains(subject, "..") || strings.ContainsAny(subject, `/\`) {
+		return "", fmt.Errorf("%w %q", ErrInvalidSubject, subject)
+	}
+	return filepath.Join(f.dir, subject), nil
+}
+
+func (f *FileStore) path(subject string, version int) (string, error) {
+	dir, err := f.subjectDir(subject)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(version)+".json"), nil
+}
+
+func (f *FileStore) Get(ctx context.Context, subject string, version int) (*Schema, error) {
+	path, err := f.path(subject, version)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (f *FileStore) Versions(ctx context.Context, subject string) ([]int, error) {
+	dir, err := f.subjectDir(subject)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []int
+	for _, e := range entries {
+		if v, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json")); err == nil && !e.IsDir() {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// Put writes the schema to a temporary file and links it to its version path, so
+// readers never see a partial file and an existing version is not replaced.
+func (f *FileStore) Put(ctx context.Context, s *Schema) error {
+	path, err := f.path(s.Subject, s.Version)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return ErrVersionExists
+		}
+		return err
+	}
+	return nil
+}
+
+// RedisStore keeps the versions of a subject in the hash schema:<subject>.
+type RedisStore struct {
+	store redisdb.Store
+}
+
+// NewRedisStore works with *redisdb.RedisClient or memstore.Store.
+func NewRedisStore(store redisdb.Store) *RedisStore {
+	return &RedisStore{store: store}
+}
+
+func redisKey(subject string) string {
+	return "schema:" + subject
+}
+
+func (r *RedisStore) Get(ctx context.Context, subject string, version int) (*Schema, error) {
+	val, err := r.store.HGet(ctx, redisKey(subject), strconv.Itoa(version))
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{}
+	if err := json.Unmarshal([]byte(val), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *RedisStore) Versions(ctx context.Context, subject string) ([]int, error) {
+	fields, err := r.store.HGetAll(ctx, redisKey(subject))
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(fields))
+	for field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("schema: invalid version %q of %s", field, subject)
+		}
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (r *RedisStore) Put(ctx context.Context, s *Schema) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	ok, err := r.store.HSetNX(ctx, redisKey(s.Subject), strconv.Itoa(s.Version), string(b))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrVersionExists
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/nartvt/go-core/database/redisdb/memstore"
+	"github.com/nartvt/go-core/pubsub"
+	"github.com/nartvt/go-core/pubsub/membroker"
+	"github.com/nartvt/go-core/uerror"
+)
+
+const orderV1 = `{
+	"type": "object",
+	"properties": {"id": {"type": "string"}, "amount": {"type": "integer"}},
+	"required": ["id"]
+}`
+
+func jsonSchema(def string) *Schema {
+	return &Schema{Subject: "orders.created", Format: FormatJSON, Definition: []byte(def)}
+}
+
+func TestCheckCompatibility_JSON(t *testing.T) {
+	prev := jsonSchema(orderV1)
+
+	optional := jsonSchema(`{"type": "object", "properties": {"id": {"type": "string"}, "amount": {"type": "number"}, "note": {"type": "string"}}, "required": ["id"]}`)
+	require.Nil(t, CheckCompatibility(prev, optional, CompatibilityBackward))
+
+	required := jsonSchema(`{"type": "object", "properties": {"id": {"type": "string"}, "note": {"type": "string"}}, "required": ["id", "note"]}`)
+	err := CheckCompatibility(prev, required, CompatibilityBackward)
+	var incompatible *IncompatibleError
+	require.True(t, errors.As(err, &incompatible))
+	require.Equal(t, []string{"$.note: property is required"}, incompatible.Problems)
+	// old consumers ignore the new property
+	require.Nil(t, CheckCompatibility(prev, required, CompatibilityForward))
+
+	retyped := jsonSchema(`{"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]}`)
+	require.NotNil(t, CheckCompatibility(prev, retyped, CompatibilityFull))
+	require.Nil(t, CheckCompatibility(prev, retyped, CompatibilityNone))
+}
+
+func TestCheckCompatibility_Protobuf(t *testing.T) {
+	def := func(s *Schema, message string, set []byte) *Schema {
+		s.Format, s.Message, s.Definition = FormatProtobuf, message, set
+		return s
+	}
+	ts, err := DescriptorSet((&timestamppb.Timestamp{}).ProtoReflect().Descriptor())
+	require.Nil(t, err)
+	// Int64Value is {int64 value = 1}, Timestamp is {int64 seconds = 1; int32 nanos = 2}
+	i64, err := DescriptorSet((&wrapperspb.Int64Value{}).ProtoReflect().Descriptor())
+	require.Nil(t, err)
+	str, err := DescriptorSet((&wrapperspb.StringValue{}).ProtoReflect().Descriptor())
+	require.Nil(t, err)
+
+	prev := def(&Schema{Subject: "clock"}, "google.protobuf.Int64Value", i64)
+	require.Nil(t, CheckCompatibility(prev, def(&Schema{Subject: "clock"}, "google.protobuf.Timestamp", ts), CompatibilityFull))
+	require.NotNil(t, CheckCompatibility(prev, def(&Schema{Subject: "clock"}, "google.protobuf.StringValue", str), CompatibilityBackward))
+}
+
+func TestRegistry_RegisterAndValidate(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]Store{
+		"file":  NewFileStore(t.TempDir()),
+		"redis": NewRedisStore(memstore.New()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := NewRegistry(store)
+			require.Nil(t, r.Register(ctx, jsonSchema(orderV1)))
+			require.NotNil(t, r.Register(ctx, jsonSchema(`{"type": "object", "required": ["id", "note"]}`)))
+
+			v2 := jsonSchema(`{"type": "object", "properties": {"id": {"type": "string"}, "note": {"type": "string"}}, "required": ["id"]}`)
+			require.Nil(t, r.Register(ctx, v2))
+			require.Equal(t, 2, v2.Version)
+			latest, err := r.Latest(ctx, "orders.created")
+			require.Nil(t, err)
+			require.Equal(t, 2, latest.Version)
+
+			// a new registry compiles the stored schemas
+			r = NewRegistry(store)
+			require.Nil(t, r.Validate(ctx, "orders.created", pubsub.NewMessage([]byte(`{"id": "1"}`))))
+			require.Nil(t, r.Validate(ctx, "orders.shipped", pubsub.NewMessage([]byte(`"anything"`))))
+
+			err = r.Validate(ctx, "orders.created", pubsub.NewMessage([]byte(`{"id": 1}`)))
+			var se *uerror.StatusError
+			require.True(t, errors.As(err, &se))
+
+			msg := pubsub.NewMessage([]byte(`{"id": "1"}`))
+			msg.Header.Set(VersionHeader, "3")
+			require.NotNil(t, r.Validate(ctx, "orders.created", msg))
+		})
+	}
+}
+
+func TestStore_PutDoesNotOverwrite(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range map[string]Store{
+		"file":  NewFileStore(t.TempDir()),
+		"redis": NewRedisStore(memstore.New()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			v1 := jsonSchema(orderV1)
+			v1.Version = 1
+			require.Nil(t, store.Put(ctx, v1))
+			other := jsonSchema(`{"type": "string"}`)
+			other.Version = 1
+			require.ErrorIs(t, store.Put(ctx, other), ErrVersionExists)
+
+			got, err := store.Get(ctx, "orders.created", 1)
+			require.Nil(t, err)
+			require.JSONEq(t, orderV1, string(got.Definition))
+			versions, err := store.Versions(ctx, "orders.created")
+			require.Nil(t, err)
+			require.Equal(t, []int{1}, versions)
+		})
+	}
+}
+
+func TestFileStore_RejectsPathSubjects(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	for _, subject := range []string{"../orders", "orders/created", `orders\created`, "..", ".", ""} {
+		s := jsonSchema(orderV1)
+		s.Subject, s.Version = subject, 1
+		require.ErrorIs(t, store.Put(ctx, s), ErrInvalidSubject, subject)
+		_, err := store.Get(ctx, subject, 1)
+		require.ErrorIs(t, err, ErrInvalidSubject, subject)
+		_, err = store.Versions(ctx, subject)
+		require.ErrorIs(t, err, ErrInvalidSubject, subject)
+	}
+}
+
+func TestRegistry_ValidateProtobuf(t *testing.T) {
+	ctx := context.Background()
+	set, err := DescriptorSet((&wrapperspb.StringValue{}).ProtoReflect().Descriptor())
+	require.Nil(t, err)
+	r := NewRegistry(NewFileStore(t.TempDir()))
+	require.Nil(t, r.Register(ctx, &Schema{Subject: "names", Format: FormatProtobuf, Message: "google.protobuf.StringValue", Definition: set}))
+
+	msg, err := pubsub.Encode("proto", wrapperspb.String("alice"))
+	require.Nil(t, err)
+	require.Nil(t, r.Validate(ctx, "names", msg))
+
+	msg = pubsub.NewMessage([]byte(`{"value": 1}`))
+	msg.Header.Set(pubsub.ContentTypeHeader, "application/json")
+	require.NotNil(t, r.Validate(ctx, "names", msg))
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(NewRedisStore(memstore.New()))
+	require.Nil(t, r.Register(ctx, jsonSchema(orderV1)))
+
+	broker := membroker.New(membroker.Sync())
+	publisher := pubsub.WrapPublisher(broker, Middleware(r))
+	subscriber := pubsub.WrapSubscriber(broker, Middleware(r))
+	var received []string
+	require.Nil(t, subscriber.Subscribe("orders.*", "", func(ctx context.Context, msg *pubsub.Message) error {
+		received = append(received, msg.Header.Get(VersionHeader))
+		return nil
+	}))
+
+	require.Nil(t, publisher.Publish(ctx, "orders.created", pubsub.NewMessage([]byte(`{"id": "1"}`))))
+	require.NotNil(t, publisher.Publish(ctx, "orders.created", pubsub.NewMessage([]byte(`{"amount": 1}`))))
+
+	// a message published without the middleware is rejected by consumers
+	require.NotNil(t, broker.Publish(ctx, "orders.created", pubsub.NewMessage([]byte(`{}`))))
+	require.Equal(t, []string{"1"}, received)
+}
@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// validator checks message data against a compiled schema.
+type validator interface {
+	validate(contentType string, data []byte) error
+}
+
+func compile(s *Schema) (validator, error) {
+	switch s.Format {
+	case FormatJSON:
+		return compileJSON(s)
+	case FormatProtobuf:
+		md, err := messageDescriptor(s)
+		if err != nil {
+			return nil, err
+		}
+		return protoValidator{md: md}, nil
+	}
+	return nil, fmt.Errorf("schema: unknown format %q", s.Format)
+}
+
+type jsonValidator struct {
+	schema *jsonschema.Schema
+}
+
+func compileJSON(s *Schema) (validator, error) {
+	url := fmt.Sprintf("mem://%s/%d.json", s.Subject, s.Version)
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, bytes.NewReader(s.Definition)); err != nil {
+		return nil, err
+	}
+	sch, err := c.Compile(url)
+	if err != nil {
+		return nil, err
+	}
+	return jsonValidator{schema: sch}, nil
+}
+
+func (v jsonValidator) validate(_ string, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	return v.schema.Validate(doc)
+}
+
+type protoValidator struct {
+	md protoreflect.MessageDescriptor
+}
+
+// validate decodes data as the message, json content is decoded with protojson.
+func (v protoValidator) validate(contentType string, data []byte) error {
+	m := dynamicpb.NewMessage(v.md)
+	if strings.Contains(contentType, "json") {
+		return protojson.Unmarshal(data, m)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+func messageDescriptor(s *Schema) (protoreflect.MessageDescriptor, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(s.Definition, set); err != nil {
+		return nil, err
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(s.Message))
+	if err != nil {
+		return nil, err
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("schema: %s is not a message", s.Message)
+	}
+	return md, nil
+}
+
+// DescriptorSet serializes the file of md and its dependencies, the result is the
+// Definition of a protobuf schema of md.
+func DescriptorSet(md protoreflect.MessageDescriptor) ([]byte, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(md.ParentFile())
+	return proto.Marshal(set)
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/nartvt/go-core/uerror"
+)
+
+// Handler handles a received message, a returned error is logged by the subscriber.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Subscriber registers handlers on subjects, handlers of the same queue group share the messages.
+// Close stops receiving and waits for the running handlers.
+type Subscriber interface {
+	Subscribe(subject, queueGroup string, handler Handler) error
+	io.Closer
+}
+
+// ErrUnsubscribeUnsupported is returned by Unsubscribe when the subscriber cannot remove handlers.
+var ErrUnsubscribeUnsupported = errors.New("pubsub: subscriber does not support unsubscribe")
+
+// Unsubscriber is implemented by subscribers that can remove the handlers registered
+// on a subject and queue group while they keep running.
+type Unsubscriber interface {
+	Unsubscribe(subject, queueGroup string) error
+}
+
+// Unsubscribe removes the handlers of subject and queueGroup from s, it returns
+// ErrUnsubscribeUnsupported when s does not implement Unsubscriber.
+func Unsubscribe(s Subscriber, subject, queueGroup string) error {
+	u, ok := s.(Unsubscriber)
+	if !ok {
+		return ErrUnsubscribeUnsupported
+	}
+	return u.Unsubscribe(subject, queueGroup)
+}
+
+// Subscribe registers a typed handler, payloads are decoded into T with the codec
+// of the content type header, json when it is missing.
+func Subscribe[T any](s Subscriber, subject, queueGroup string, handler func(ctx context.Context, v T) error) error {
+	return s.Subscribe(subject, queueGroup, func(ctx context.Context, msg *Message) error {
+		v, err := DecodeValue[T](msg)
+		if err != nil {
+			return uerror.BadRequestError(err.Error())
+		}
+		return handler(ctx, v)
+	})
+}
@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+// KindPubSub is the transport kind set by WrapPublisher and WrapSubscriber.
+const KindPubSub transport.Kind = "pubsub"
+
+var _ transport.Transporter = (*Transport)(nil)
+
+// Transport is a message transport, the operation is the message subject.
+// It is a client transport on the publish path and a server transport on the consume path.
+type Transport struct {
+	operation   string
+	reqHeader   Header
+	replyHeader Header
+}
+
+func (tr *Transport) Kind() transport.Kind {
+	return KindPubSub
+}
+
+func (tr *Transport) Endpoint() string {
+	return ""
+}
+
+func (tr *Transport) Operation() string {
+	return tr.operation
+}
+
+func (tr *Transport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+func (tr *Transport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
+
+type publisher struct {
+	Publisher
+	next middleware.Handler
+}
+
+// WrapPublisher runs ms around every Publish of p. Middleware get the *Message as
+// request and a client transport whose request header is the message header, so
+// client middleware such as tracing can inject metadata.
+func WrapPublisher(p Publisher, ms ...middleware.Middleware) Publisher {
+	next := func(ctx context.Context, req interface{}) (interface{}, error) {
+		msg := req.(*Message)
+		tr, _ := transport.FromClientContext(ctx)
+		return nil, p.Publish(ctx, tr.Operation(), msg)
+	}
+	return &publisher{Publisher: p, next: middleware.Chain(ms...)(next)}
+}
+
+func (p *publisher) Publish(ctx context.Context, topic string, msg *Message) error {
+	if msg.Header == nil {
+		msg.Header = Header{}
+	}
+	ctx = transport.NewClientContext(ctx, &Transport{
+		operation:   topic,
+		reqHeader:   msg.Header,
+		replyHeader: Header{},
+	})
+	_, err := p.next(ctx, msg)
+	return err
+}
+
+type subscriber struct {
+	Subscriber
+	ms []middleware.Middleware
+}
+
+// WrapSubscriber runs ms around every handler registered on s. When the subscriber
+// sets no server transport, like the NATS server does, a pubsub server transport is used.
+func WrapSubscriber(s Subscriber, ms ...middleware.Middleware) Subscriber {
+	return &subscriber{Subscriber: s, ms: ms}
+}
+
+func (s *subscriber) Subscribe(subject, queueGroup string, handler Handler) error {
+	next := middleware.Chain(s.ms...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, handler(ctx, req.(*Message))
+	})
+	return s.Subscriber.Subscribe(subject, queueGroup, func(ctx context.Context, msg *Message) error {
+		if _, ok := transport.FromServerContext(ctx); !ok {
+			ctx = transport.NewServerContext(ctx, &Transport{
+				operation:   msg.Subject,
+				reqHeader:   msg.Header,
+				replyHeader: Header{},
+			})
+		}
+		_, err := next(ctx, msg)
+		return err
+	})
+}
+
+func (s *subscriber) Unsubscribe(subject, queueGroup string) error {
+	return Unsubscribe(s.Subscriber, subject, queueGroup)
+}
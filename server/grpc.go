@@ -2,31 +2,21 @@ package server
 
 import (
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/middleware/logging"
-	"github.com/go-kratos/kratos/v2/middleware/metrics"
-	"github.com/go-kratos/kratos/v2/middleware/recovery"
-	"github.com/go-kratos/kratos/v2/middleware/tracing"
-	"github.com/go-kratos/kratos/v2/middleware/validate"
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport/grpc"
 	"github.com/nartvt/go-core/conf"
-	"github.com/nartvt/go-core/middleware/jwt"
 )
 
 // NewGRPCServer new a gRPC server.
 func NewGRPCServer(c *conf.Server, logger log.Logger) *grpc.Server {
-	authMiddleware := jwt.Server()
-	if c.Auth != nil {
-		authMiddleware = jwt.Server(jwt.WithRequired(c.Auth.Required), jwt.WithExcludes(c.Auth.Excludes), jwt.WithAutoParse(c.Auth.AutoParse))
-	}
+	return NewGRPCServerWithMiddleware(c, logger)
+}
+
+// NewGRPCServerWithMiddleware new a gRPC server, extra middlewares such as idempotency run after the auth middleware.
+func NewGRPCServerWithMiddleware(c *conf.Server, logger log.Logger, ms ...middleware.Middleware) *grpc.Server {
+	middlewares := append(serverMiddlewares(c, logger), ms...)
 	var opts = []grpc.ServerOption{
-		grpc.Middleware(
-			recovery.Recovery(),
-			tracing.Server(),
-			logging.Server(logger),
-			metrics.Server(),
-			validate.Validator(),
-			authMiddleware,
-		),
+		grpc.Middleware(middlewares...),
 	}
 	if c.Grpc.Network != "" {
 		opts = append(opts, grpc.Network(c.Grpc.Network))
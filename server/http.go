@@ -5,31 +5,21 @@ import (
 	"net/http"
 
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/go-kratos/kratos/v2/middleware/logging"
-	"github.com/go-kratos/kratos/v2/middleware/metrics"
-	"github.com/go-kratos/kratos/v2/middleware/recovery"
-	"github.com/go-kratos/kratos/v2/middleware/tracing"
-	"github.com/go-kratos/kratos/v2/middleware/validate"
+	"github.com/go-kratos/kratos/v2/middleware"
 	khttp "github.com/go-kratos/kratos/v2/transport/http"
 	"github.com/nartvt/go-core/conf"
-	"github.com/nartvt/go-core/middleware/jwt"
 )
 
 // NewHTTPServer new a HTTP server.
 func NewHTTPServer(c *conf.Server, logger log.Logger) *khttp.Server {
-	authMiddleware := jwt.Server()
-	if c.Auth != nil {
-		authMiddleware = jwt.Server(jwt.WithRequired(c.Auth.Required), jwt.WithExcludes(c.Auth.Excludes), jwt.WithAutoParse(c.Auth.AutoParse))
-	}
+	return NewHTTPServerWithMiddleware(c, logger)
+}
+
+// NewHTTPServerWithMiddleware new a HTTP server, extra middlewares such as idempotency run after the auth middleware.
+func NewHTTPServerWithMiddleware(c *conf.Server, logger log.Logger, ms ...middleware.Middleware) *khttp.Server {
+	middlewares := append(serverMiddlewares(c, logger), ms...)
 	var opts = []khttp.ServerOption{
-		khttp.Middleware(
-			recovery.Recovery(),
-			tracing.Server(),
-			logging.Server(logger),
-			metrics.Server(),
-			validate.Validator(),
-			authMiddleware,
-		),
+		khttp.Middleware(middlewares...),
 	}
 	if c.Http.Network != "" {
 		opts = append(opts, khttp.Network(c.Http.Network))
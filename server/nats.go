@@ -0,0 +1,27 @@
+package server
+
+import (
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/pubsub/nats"
+)
+
+// NewNATSServer new a NATS server.
+func NewNATSServer(c *conf.Server, logger log.Logger) *nats.Server {
+	return NewNATSServerWithMiddleware(c, logger)
+}
+
+// NewNATSServerWithMiddleware new a NATS server, message handlers get the same middleware chain as HTTP and gRPC.
+func NewNATSServerWithMiddleware(c *conf.Server, logger log.Logger, ms ...middleware.Middleware) *nats.Server {
+	natsConf := c.Nats
+	if natsConf == nil {
+		natsConf = &conf.Nats{}
+	}
+	middlewares := append(serverMiddlewares(c, logger), ms...)
+	srv := nats.NewServer(natsConf,
+		nats.Logger(logger),
+		nats.Middleware(middlewares...),
+	)
+	return srv
+}
@@ -1,8 +1,33 @@
 package server
 
 import (
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/logging"
+	"github.com/go-kratos/kratos/v2/middleware/metrics"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/middleware/tracing"
+	"github.com/go-kratos/kratos/v2/middleware/validate"
 	"github.com/google/wire"
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/middleware/jwt"
 )
 
 // ProviderSet is server providers.
-var ProviderSet = wire.NewSet(NewHTTPServer, NewGRPCServer)
+var ProviderSet = wire.NewSet(NewHTTPServer, NewGRPCServer, NewNATSServer)
+
+// serverMiddlewares is the middleware chain shared by every transport.
+func serverMiddlewares(c *conf.Server, logger log.Logger) []middleware.Middleware {
+	authMiddleware := jwt.Server()
+	if c.Auth != nil {
+		authMiddleware = jwt.Server(jwt.WithRequired(c.Auth.Required), jwt.WithExcludes(c.Auth.Excludes), jwt.WithAutoParse(c.Auth.AutoParse))
+	}
+	return []middleware.Middleware{
+		recovery.Recovery(),
+		tracing.Server(),
+		logging.Server(logger),
+		metrics.Server(),
+		validate.Validator(),
+		authMiddleware,
+	}
+}
@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
+	"io"
 	"os"
 
 	"github.com/go-kratos/kratos/v2"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/go-kratos/kratos/v2/transport/grpc"
 	"github.com/go-kratos/kratos/v2/transport/http"
 	"github.com/pkg/errors"
@@ -32,15 +35,17 @@ type CommonService struct {
 }
 
 func NewService(logger log.Logger, hs *http.Server, gs *grpc.Server, options ...kratos.Option) Service {
+	return NewServiceWithServers(logger, []transport.Server{hs, gs}, options...)
+}
+
+// NewServiceWithServers new a service running any transport servers, e.g. HTTP, gRPC and the NATS server.
+func NewServiceWithServers(logger log.Logger, servers []transport.Server, options ...kratos.Option) Service {
 	options = append(options, kratos.Metadata(map[string]string{}),
 		kratos.ID(id),
 		kratos.Name(Name),
 		kratos.Version(Version),
 		kratos.Logger(log.DefaultLogger),
-		kratos.Server(
-			hs,
-			gs,
-		))
+		kratos.Server(servers...))
 	app := kratos.New(
 		options...,
 	)
@@ -50,6 +55,19 @@ func NewService(logger log.Logger, hs *http.Server, gs *grpc.Server, options ...
 	}
 }
 
+// CloseOnStop closes resources such as publishers after the app has stopped its servers.
+func CloseOnStop(closers ...io.Closer) kratos.Option {
+	return kratos.AfterStop(func(ctx context.Context) error {
+		var firstErr error
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	})
+}
+
 func (s CommonService) Run() error {
 	// start and wait for stop signal
 	return s.app.Run()
@@ -0,0 +1,252 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Device describes the client a session was created from.
+type Device struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Device     Device    `json:"device"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Option is session store option.
+type Option func(*Store)
+
+// WithTTL sets the idle timeout, every Touch extends the session by ttl.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = ttl
+	}
+}
+
+// WithKeyPrefix changes the redis key prefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store keeps sessions in redis, a session is stored as json under
+// <prefix><id> and every user has a sorted set of session ids scored by expiry.
+type Store struct {
+	client *redis.Client
+	keys   *redisdb.KeyBuilder
+	ttl    time.Duration
+	prefix string
+}
+
+func NewStore(client *redisdb.RedisClient, opts ...Option) *Store {
+	s := &Store{
+		client: client.GetClient(),
+		keys:   client.KeyBuilder(),
+		ttl:    30 * 24 * time.Hour,
+		prefix: "session:",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Store) sessionKey(id string) string {
+	return s.keys.Key(s.prefix + id)
+}
+
+func (s *Store) userKey(userID string) string {
+	return s.keys.Key(s.prefix + "user:" + userID)
+}
+
+// Create starts a new session for userID on device.
+func (s *Store) Create(ctx context.Context, userID string, device Device) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sess := &Session{
+		ID:         id,
+		UserID:     userID,
+		Device:     device,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+	if err := s.save(ctx, sess, true); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (s *Store) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := s.client.Get(ctx, s.sessionKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess := &Session{}
+	if err := json.Unmarshal(data, sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// Touch marks the session as used and slides its expiry.
+func (s *Store) Touch(ctx context.Context, id string) (*Session, error) {
+	sess, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sess.LastSeenAt = time.Now()
+	sess.ExpiresAt = sess.LastSeenAt.Add(s.ttl)
+	if err := s.save(ctx, sess, false); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// List returns the active sessions of userID, expired entries are pruned.
+func (s *Store) List(ctx context.Context, userID string) ([]*Session, error) {
+	userKey := s.userKey(userID)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.client.ZRemRangeByScore(ctx, userKey, "-inf", "("+now).Err(); err != nil {
+		return nil, err
+	}
+	ids, err := s.client.ZRange(ctx, userKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		sess, err := s.Get(ctx, id)
+		if err == ErrSessionNotFound {
+			s.client.ZRem(ctx, userKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, nil
+}
+
+// Revoke deletes a single session.
+func (s *Store) Revoke(ctx context.Context, id string) error {
+	sess, err := s.Get(ctx, id)
+	if err == ErrSessionNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, s.sessionKey(id))
+	pipe.ZRem(ctx, s.userKey(sess.UserID), id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeAll deletes every session of userID except the ones in keep.
+func (s *Store) RevokeAll(ctx context.Context, userID string, keep ...string) error {
+	userKey := s.userKey(userID)
+	ids, err := s.client.ZRange(ctx, userKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	kept := make(map[string]struct{}, len(keep))
+	for _, id := range keep {
+		kept[id] = struct{}{}
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, id := range ids {
+		if _, ok := kept[id]; ok {
+			continue
+		}
+		pipe.Del(ctx, s.sessionKey(id))
+		pipe.ZRem(ctx, userKey, id)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// IsActive reports whether the session exists, it matches jwt.SessionValidator
+// so it can be passed to jwt.WithSessionValidator.
+func (s *Store) IsActive(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.sessionKey(id)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// saveScript writes the session and indexes it in the user set atomically, an update
+// (ARGV[5] == "0") never brings back a session revoked in between.
+var saveScript = redis.NewScript(`
+if ARGV[5] == "0" and redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[4])
+redis.call("PEXPIRE", KEYS[2], ARGV[2])
+return 1`)
+
+// save writes the session, the latest saved session of a user always expires last
+// so the user index lives as long as it.
+func (s *Store) save(ctx context.Context, sess *Session, create bool) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	flag := "0"
+	if create {
+		flag = "1"
+	}
+	saved, err := saveScript.Run(ctx, s.client,
+		[]string{s.sessionKey(sess.ID), s.userKey(sess.UserID)},
+		data, s.ttl.Milliseconds(), sess.ExpiresAt.Unix(), sess.ID, flag,
+	).Int()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
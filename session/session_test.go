@@ -0,0 +1,96 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+func newStore(t *testing.T, opts ...Option) (*miniredis.Miniredis, *Store) {
+	mr := miniredis.RunT(t)
+	client := redisdb.WrapClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	return mr, NewStore(client, opts...)
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	mr, s := newStore(t, WithTTL(time.Hour))
+
+	web, err := s.Create(ctx, "u1", Device{Name: "web"})
+	require.Nil(t, err)
+	phone, err := s.Create(ctx, "u1", Device{Name: "phone"})
+	require.Nil(t, err)
+	_, err = s.Create(ctx, "u2", Device{Name: "web"})
+	require.Nil(t, err)
+
+	got, err := s.Get(ctx, web.ID)
+	require.Nil(t, err)
+	require.Equal(t, "web", got.Device.Name)
+	active, err := s.IsActive(ctx, web.ID)
+	require.Nil(t, err)
+	require.True(t, active)
+
+	mr.FastForward(30 * time.Minute)
+	touched, err := s.Touch(ctx, web.ID)
+	require.Nil(t, err)
+	require.True(t, touched.ExpiresAt.After(web.ExpiresAt))
+	require.Equal(t, time.Hour, mr.TTL(s.sessionKey(web.ID)))
+
+	sessions, err := s.List(ctx, "u1")
+	require.Nil(t, err)
+	require.Len(t, sessions, 2)
+
+	require.Nil(t, s.Revoke(ctx, phone.ID))
+	_, err = s.Get(ctx, phone.ID)
+	require.Equal(t, ErrSessionNotFound, err)
+	_, err = s.Touch(ctx, phone.ID)
+	require.Equal(t, ErrSessionNotFound, err)
+	sessions, err = s.List(ctx, "u1")
+	require.Nil(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, web.ID, sessions[0].ID)
+}
+
+func TestStore_TouchAfterRevokeKeepsIndexClean(t *testing.T) {
+	ctx := context.Background()
+	mr, s := newStore(t)
+
+	sess, err := s.Create(ctx, "u1", Device{})
+	require.Nil(t, err)
+	require.Nil(t, s.Revoke(ctx, sess.ID))
+
+	// a Touch racing the Revoke already read the session
+	sess.LastSeenAt = time.Now()
+	require.Equal(t, ErrSessionNotFound, s.save(ctx, sess, false))
+	members, err := mr.ZMembers(s.userKey("u1"))
+	require.True(t, err == miniredis.ErrKeyNotFound || len(members) == 0)
+}
+
+func TestStore_RevokeAll(t *testing.T) {
+	ctx := context.Background()
+	_, s := newStore(t)
+
+	current, err := s.Create(ctx, "u1", Device{Name: "current"})
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = s.Create(ctx, "u1", Device{})
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, s.RevokeAll(ctx, "u1", current.ID))
+	sessions, err := s.List(ctx, "u1")
+	require.Nil(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, current.ID, sessions[0].ID)
+
+	require.Nil(t, s.RevokeAll(ctx, "u1"))
+	active, err := s.IsActive(ctx, current.ID)
+	require.Nil(t, err)
+	require.False(t, active)
+}
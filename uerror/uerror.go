@@ -67,6 +67,11 @@ func UnAuthorizeError(message string) *StatusError {
 	return newError(http.StatusUnauthorized, UNAUTHORIZED_ERROR, errors.New(message))
 }
 
+// PanicError wraps a recovered panic value.
+func PanicError(r interface{}) *StatusError {
+	return newError(http.StatusInternalServerError, INTERNAL_SERVER_ERROR, fmt.Errorf("panic: %v", r))
+}
+
 func (s *StatusError) Error() string {
 	if s.Err != nil {
 		return s.Err.Error()
@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+const (
+	BatchOpGet    = "get"
+	BatchOpSet    = "set"
+	BatchOpHSet   = "hset"
+	BatchOpExpire = "expire"
+
+	defaultBatchChunkSize = 100
+)
+
+// BatchOption is batch option.
+type BatchOption func(*Batch)
+
+// WithBatchKeyBuilder namespaces every key queued in the batch.
+func WithBatchKeyBuilder(keys *redisdb.KeyBuilder) BatchOption {
+	return func(b *Batch) {
+		b.keys = keys
+	}
+}
+
+// WithChunkSize limits the number of commands sent in one round-trip.
+func WithChunkSize(size int) BatchOption {
+	return func(b *Batch) {
+		if size > 0 {
+			b.chunkSize = size
+		}
+	}
+}
+
+// Batch queues many commands and flushes them with pipelined round-trips.
+// A *redis.ClusterClient pipeline splits the commands by node and sends them to
+// the nodes concurrently, so keys of any slot share a round-trip.
+type Batch struct {
+	client    redis.UniversalClient
+	keys      *redisdb.KeyBuilder
+	chunkSize int
+	ops       []*batchOp
+	err       error
+}
+
+type batchOp struct {
+	op     string
+	key    string
+	value  interface{}
+	values []interface{}
+	ttl    time.Duration
+}
+
+// BatchResult holds the outcome of a single queued command.
+type BatchResult struct {
+	Op  string
+	Key string
+	Err error
+
+	cmd redis.Cmder
+}
+
+func NewBatch(client redis.UniversalClient, opts ...BatchOption) *Batch {
+	b := &Batch{
+		client:    client,
+		chunkSize: defaultBatchChunkSize,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Batch returns a new batch bound to the helper client.
+func (h *RedisHelper) Batch(opts ...BatchOption) *Batch {
+	opts = append([]BatchOption{WithBatchKeyBuilder(h.Keys)}, opts...)
+	if h.Client == nil {
+		return NewBatch(nil, opts...)
+	}
+	return NewBatch(h.Client, opts...)
+}
+
+func (b *Batch) Get(key string) *Batch {
+	b.ops = append(b.ops, &batchOp{op: BatchOpGet, key: b.keys.Key(key)})
+	return b
+}
+
+// Set queues a SET, value is json encoded the same way as RedisHelper.SetWithContext.
+func (b *Batch) Set(key string, value interface{}, expiration time.Duration) *Batch {
+	data, err := json.Marshal(value)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.ops = append(b.ops, &batchOp{op: BatchOpSet, key: b.keys.Key(key), value: string(data), ttl: expiration})
+	return b
+}
+
+func (b *Batch) HSet(key string, values ...interface{}) *Batch {
+	b.ops = append(b.ops, &batchOp{op: BatchOpHSet, key: b.keys.Key(key), values: values})
+	return b
+}
+
+func (b *Batch) Expire(key string, expiration time.Duration) *Batch {
+	b.ops = append(b.ops, &batchOp{op: BatchOpExpire, key: b.keys.Key(key), ttl: expiration})
+	return b
+}
+
+// Len returns the number of queued commands.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Exec flushes the queued commands and returns one result per command in the
+// order they were queued. The returned error is only set when the batch could
+// not be sent at all, per-command failures are reported in BatchResult.Err.
+// A value Set could not encode fails Exec, either way the queue is emptied and
+// the batch can be reused.
+func (b *Batch) Exec(ctx context.Context) ([]*BatchResult, error) {
+	ops, err := b.ops, b.err
+	b.ops, b.err = nil, nil
+	if b.client == nil {
+		return nil, errors.New("Redis Client is null")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchResult, len(ops))
+	for start := 0; start < len(ops); start += b.chunkSize {
+		end := start + b.chunkSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if err := b.flush(ctx, ops, start, end, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// flush sends ops[start:end] in one pipeline.
+func (b *Batch) flush(ctx context.Context, ops []*batchOp, start, end int, results []*BatchResult) error {
+	pipe := b.client.Pipeline()
+	for i := start; i < end; i++ {
+		op := ops[i]
+		var cmd redis.Cmder
+		switch op.op {
+		case BatchOpGet:
+			cmd = pipe.Get(ctx, op.key)
+		case BatchOpSet:
+			cmd = pipe.Set(ctx, op.key, op.value, op.ttl)
+		case BatchOpHSet:
+			cmd = pipe.HSet(ctx, op.key, op.values...)
+		case BatchOpExpire:
+			cmd = pipe.Expire(ctx, op.key, op.ttl)
+		}
+		results[i] = &BatchResult{Op: op.op, Key: b.keys.Trim(op.key), cmd: cmd}
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for i := start; i < end; i++ {
+		if cmdErr := results[i].cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			results[i].Err = cmdErr
+		}
+	}
+	return nil
+}
+
+// Found reports whether a GET result hit an existing key.
+func (r *BatchResult) Found() bool {
+	return r.Err == nil && r.cmd.Err() != redis.Nil
+}
+
+// String returns the raw value of a GET result.
+func (r *BatchResult) String() (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	cmd, ok := r.cmd.(*redis.StringCmd)
+	if !ok {
+		return "", errors.New("batch result is not a string")
+	}
+	return cmd.Result()
+}
+
+// Scan json decodes a GET result into dst.
+func (r *BatchResult) Scan(dst interface{}) error {
+	data, err := r.String()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), dst)
+}
+
+// Int64 returns the number of fields added by a HSET.
+func (r *BatchResult) Int64() (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	cmd, ok := r.cmd.(*redis.IntCmd)
+	if !ok {
+		return 0, errors.New("batch result is not an integer")
+	}
+	return cmd.Result()
+}
+
+// Bool returns whether an EXPIRE was applied.
+func (r *BatchResult) Bool() (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	cmd, ok := r.cmd.(*redis.BoolCmd)
+	if !ok {
+		return false, errors.New("batch result is not a boolean")
+	}
+	return cmd.Result()
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineCounter counts the pipelines sent by a client.
+type pipelineCounter struct {
+	sizes []int
+}
+
+func (c *pipelineCounter) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (c *pipelineCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (c *pipelineCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.sizes = append(c.sizes, len(cmds))
+		return next(ctx, cmds)
+	}
+}
+
+func TestBatch_Exec(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	counter := &pipelineCounter{}
+	client.AddHook(counter)
+	mr.Set("existing", `{"name":"a"}`)
+
+	results, err := NewBatch(client, WithChunkSize(2)).
+		Get("existing").
+		Get("missing").
+		Set("new", map[string]string{"name": "b"}, time.Minute).
+		HSet("hash", "f1", "v1", "f2", "v2").
+		Expire("existing", time.Minute).
+		Exec(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []int{2, 2, 1}, counter.sizes)
+	require.Len(t, results, 5)
+
+	var value map[string]string
+	require.True(t, results[0].Found())
+	require.Nil(t, results[0].Scan(&value))
+	require.Equal(t, "a", value["name"])
+	require.False(t, results[1].Found())
+	require.Nil(t, results[1].Err)
+	require.Equal(t, BatchOpSet, results[2].Op)
+	require.Nil(t, results[2].Err)
+	added, err := results[3].Int64()
+	require.Nil(t, err)
+	require.Equal(t, int64(2), added)
+	applied, err := results[4].Bool()
+	require.Nil(t, err)
+	require.True(t, applied)
+	_, err = results[4].String()
+	require.NotNil(t, err)
+
+	stored, err := mr.Get("new")
+	require.Nil(t, err)
+	require.JSONEq(t, `{"name":"b"}`, stored)
+	require.Equal(t, time.Minute, mr.TTL("existing"))
+}
+
+func TestBatch_ExecResetsEncodeError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	b := NewBatch(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	_, err := b.Set("bad", make(chan int), 0).Set("good", 1, 0).Exec(context.Background())
+	require.NotNil(t, err)
+	require.Zero(t, b.Len())
+
+	results, err := b.Set("good", 1, 0).Exec(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	require.Nil(t, results[0].Err)
+}
+
+func TestBatch_ClusterPipelinesEverySlot(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	counter := &pipelineCounter{}
+	client.AddHook(counter)
+
+	// keys of different slots are sent in one pipeline, the client splits it by node
+	results, err := NewBatch(client).Set("{a}1", 1, 0).Set("{b}1", 2, 0).Set("{a}2", 3, 0).Exec(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, "{b}1", results[1].Key)
+	for _, r := range results {
+		require.Nil(t, r.Err)
+	}
+	require.Equal(t, []int{3}, counter.sizes)
+}
@@ -8,10 +8,14 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/database/redisdb"
 )
 
 type RedisHelper struct {
 	Client *redis.Client
+	// Keys namespaces every key passed to the helper, nil keeps keys as they are.
+	Keys *redisdb.KeyBuilder
 }
 
 func NewRedisHelper(client *redis.Client) *RedisHelper {
@@ -20,87 +24,63 @@ func NewRedisHelper(client *redis.Client) *RedisHelper {
 	}
 }
 
-func InitRedis(ctx context.Context, options *redis.Options) (*redis.Client, error) {
-	rdbclient := redis.NewClient(options)
-	_, err := rdbclient.Ping(ctx).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	return rdbclient, nil
+// WithKeyBuilder namespaces every key passed to the helper.
+func (h *RedisHelper) WithKeyBuilder(keys *redisdb.KeyBuilder) *RedisHelper {
+	h.Keys = keys
+	return h
 }
 
-func (h *RedisHelper) Close() error {
-	if h.Client != nil {
-		err := h.Client.Close()
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+var _ redisdb.Store = (*RedisHelper)(nil)
+
+// Store returns the helper as a redisdb.Store.
+func (h *RedisHelper) Store() redisdb.Store {
+	return h
 }
 
-func (h *RedisHelper) Exists(key string) (bool, error) {
-	if h.Client == nil {
-		return false, errors.New("Redis Client is null")
-	}
-	indicator, err := h.Client.Exists(context.Background(), key).Result()
-	if err != nil {
-		return false, err
-	}
-	if indicator <= 0 {
-		return false, nil
-	}
-	return true, nil
+func (h *RedisHelper) key(key string) string {
+	return h.Keys.Key(key)
 }
 
-func (h *RedisHelper) Get(key string) (interface{}, error) {
-	if h.Client == nil {
-		return nil, errors.New("Redis Client is null")
-	}
-	data, err := h.Client.Get(context.Background(), key).Result()
-	if err != nil && err == redis.Nil {
-		return nil, nil
+func (h *RedisHelper) keyList(keys []string) []string {
+	if h.Keys == nil {
+		return keys
 	}
-	if err != nil {
-		return nil, err
-	}
-	var value interface{}
-	err = json.Unmarshal([]byte(data), &value)
-	if err != nil {
-		return nil, err
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = h.key(k)
 	}
-	return value, nil
+	return result
 }
 
-func (h *RedisHelper) MGet(key ...string) (interface{}, error) {
-	if h.Client == nil {
-		return nil, errors.New("Redis Client is null")
+// InitRedis connects to redis, hooks such as redisdb.NewHook are added before the first ping.
+func InitRedis(ctx context.Context, options *redis.Options, hooks ...redis.Hook) (*redis.Client, error) {
+	rdbclient := redis.NewClient(options)
+	for _, hook := range hooks {
+		rdbclient.AddHook(hook)
 	}
-	data, err := h.Client.MGet(context.Background(), key...).Result()
+	_, err := rdbclient.Ping(ctx).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	return data, nil
+	return rdbclient, nil
 }
 
-func (h *RedisHelper) HGet(key string, field string) (interface{}, error) {
-	if h.Client == nil {
-		return nil, errors.New("Redis Client is null")
-	}
-	data, err := h.Client.HGet(context.Background(), key, field).Result()
-	if err != nil {
-		return nil, err
+func (h *RedisHelper) Close() error {
+	if h.Client != nil {
+		err := h.Client.Close()
+		if err != nil {
+			return err
+		}
 	}
-
-	return data, nil
+	return nil
 }
 
 func (h *RedisHelper) HMGet(key string, field ...string) ([]interface{}, error) {
 	if h.Client == nil {
 		return nil, errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := h.Client.HMGet(context.Background(), key, field...).Result()
 	if err != nil {
 		return nil, err
@@ -109,22 +89,11 @@ func (h *RedisHelper) HMGet(key string, field ...string) ([]interface{}, error)
 	return data, nil
 }
 
-func (h *RedisHelper) HSet(key string, value ...interface{}) (interface{}, error) {
-	if h.Client == nil {
-		return nil, errors.New("Redis Client is null")
-	}
-	data, err := h.Client.HSet(context.Background(), key, value...).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
-}
-
 func (h *RedisHelper) HMSet(key string, field ...interface{}) (interface{}, error) {
 	if h.Client == nil {
 		return nil, errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := h.Client.HMSet(context.Background(), key, field...).Result()
 	if err != nil {
 		return nil, err
@@ -133,23 +102,12 @@ func (h *RedisHelper) HMSet(key string, field ...interface{}) (interface{}, erro
 	return data, nil
 }
 
-func (h *RedisHelper) HGetAll(key string) (interface{}, error) {
-	if h.Client == nil {
-		return nil, errors.New("Redis Client is null")
-	}
-	data, err := h.Client.HGetAll(context.Background(), key).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
-}
-
 // return new value of key after increase old value
 func (h *RedisHelper) IncreaseInt(key string, value int) (int, error) {
 	if h.Client == nil {
 		return 0, errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	res := 0
 	//
 	ctx := context.Background()
@@ -179,6 +137,7 @@ func (h *RedisHelper) IncreaseMinValue(keys []string, value int) (string, error)
 		return "", errors.New("Redis Client is null")
 	}
 	res := 0
+	keys = h.keyList(keys)
 	//
 	key := ""
 	ctx := context.Background()
@@ -213,13 +172,14 @@ func (h *RedisHelper) IncreaseMinValue(keys []string, value int) (string, error)
 	if err != nil {
 		return "", err
 	}
-	return key, nil
+	return h.Keys.Trim(key), nil
 }
 
 func (h *RedisHelper) GetInterface(key string, value interface{}) (interface{}, error) {
 	if h.Client == nil {
 		return nil, errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := h.Client.Get(context.Background(), key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -263,71 +223,13 @@ func (h *RedisHelper) GetInterface(key string, value interface{}) (interface{},
 	return outValue, nil
 }
 
-func (h *RedisHelper) Set(key string, value interface{}, expiration time.Duration) error {
-	if h.Client == nil {
-		return errors.New("Redis Client is null")
-	}
-	data, err := json.Marshal(value)
-	if err != nil {
-		return err
-	}
-
-	_, err = h.Client.Set(context.Background(), key, string(data), expiration).Result()
-	if err != nil && err == redis.Nil {
-		return nil
-	}
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (h *RedisHelper) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
-	if h.Client == nil {
-		return false, errors.New("Redis Client is null")
-	}
-	var isSuccessful bool
-	data, err := json.Marshal(value)
-	if err != nil {
-		return false, err
-	}
-
-	isSuccessful, err = h.Client.SetNX(context.Background(), key, string(data), expiration).Result()
-	if err != nil {
-		return false, err
-	}
-	return isSuccessful, nil
-}
-
-func (h *RedisHelper) Del(key string) error {
-	if h.Client == nil {
-		return errors.New("Redis Client is null")
-	}
-	_, err := h.Client.Del(context.Background(), key).Result()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (h *RedisHelper) Expire(key string, expiration time.Duration) error {
-	if h.Client == nil {
-		return errors.New("Redis Client is null")
-	}
-	_, err := h.Client.Expire(context.Background(), key, expiration).Result()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func (h *RedisHelper) DelMulti(keys ...string) error {
 	if h.Client == nil {
 		return errors.New("Redis Client is null")
 	}
 	var err error
 	pipeline := h.Client.TxPipeline()
-	pipeline.Del(context.Background(), keys...)
+	pipeline.Del(context.Background(), h.keyList(keys)...)
 	_, err = pipeline.Exec(context.Background())
 	return err
 }
@@ -345,12 +247,14 @@ func (h *RedisHelper) GetKeysByPattern(pattern string) ([]string, uint64, error)
 
 	for {
 		var temp_keys []string
-		temp_keys, cursor, err = h.Client.Scan(context.Background(), cursor, pattern, limit).Result()
+		temp_keys, cursor, err = h.Client.Scan(context.Background(), cursor, h.Keys.Match(pattern), limit).Result()
 		if err != nil {
 			return nil, 0, err
 		}
 
-		keys = append(keys, temp_keys...)
+		for _, k := range temp_keys {
+			keys = append(keys, h.Keys.Trim(k))
+		}
 		if cursor == 0 {
 			break
 		}
@@ -364,7 +268,7 @@ func (h *RedisHelper) RenameKey(oldkey, newkey string) error {
 		return errors.New("Redis Client is null")
 	}
 	var err error
-	_, err = h.Client.Rename(context.Background(), oldkey, newkey).Result()
+	_, err = h.Client.Rename(context.Background(), h.key(oldkey), h.key(newkey)).Result()
 	return err
 }
 
@@ -372,6 +276,7 @@ func (h *RedisHelper) GetType(key string) (string, error) {
 	if h.Client == nil {
 		return "", errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	typeK, err := h.Client.Type(context.Background(), key).Result()
 	if err != nil {
 		return "", err
@@ -383,6 +288,7 @@ func (h *RedisHelper) GetWithContext(ctx context.Context, key string) (interface
 	if h.Client == nil {
 		return "", errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := h.Client.Get(ctx, key).Result()
 	if err != nil && err == redis.Nil {
 		return nil, nil
@@ -402,6 +308,7 @@ func (h *RedisHelper) GetInterfaceWithContext(ctx context.Context, key string, v
 	if h.Client == nil {
 		return "", errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := h.Client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
@@ -449,6 +356,7 @@ func (h *RedisHelper) SetWithContext(ctx context.Context, key string, value inte
 	if h.Client == nil {
 		return errors.New("Redis Client is null")
 	}
+	key = h.key(key)
 	data, err := json.Marshal(value)
 	if err != nil {
 		return err
@@ -463,3 +371,133 @@ func (h *RedisHelper) SetWithContext(ctx context.Context, key string, value inte
 	}
 	return nil
 }
+
+func (h *RedisHelper) Get(ctx context.Context, key string) (string, error) {
+	if h.Client == nil {
+		return "", errors.New("Redis Client is null")
+	}
+	return h.Client.Get(ctx, h.key(key)).Result()
+}
+
+func (h *RedisHelper) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	if h.Client == nil {
+		return nil, errors.New("Redis Client is null")
+	}
+	return h.Client.MGet(ctx, h.keyList(keys)...).Result()
+}
+
+func (h *RedisHelper) Set(ctx context.Context, key, val string, expiredInSec int32) (string, error) {
+	if h.Client == nil {
+		return "", errors.New("Redis Client is null")
+	}
+	return h.Client.Set(ctx, h.key(key), val, time.Duration(expiredInSec)*time.Second).Result()
+}
+
+func (h *RedisHelper) SetNX(ctx context.Context, key, val string, expiration time.Duration) (bool, error) {
+	if h.Client == nil {
+		return false, errors.New("Redis Client is null")
+	}
+	return h.Client.SetNX(ctx, h.key(key), val, expiration).Result()
+}
+
+func (h *RedisHelper) Del(ctx context.Context, keys ...string) (int64, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.Del(ctx, h.keyList(keys)...).Result()
+}
+
+func (h *RedisHelper) Exists(ctx context.Context, keys ...string) (int64, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.Exists(ctx, h.keyList(keys)...).Result()
+}
+
+func (h *RedisHelper) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	if h.Client == nil {
+		return false, errors.New("Redis Client is null")
+	}
+	return h.Client.Expire(ctx, h.key(key), expiration).Result()
+}
+
+func (h *RedisHelper) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.TTL(ctx, h.key(key)).Result()
+}
+
+func (h *RedisHelper) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.IncrBy(ctx, h.key(key), value).Result()
+}
+
+func (h *RedisHelper) HGet(ctx context.Context, key, field string) (string, error) {
+	if h.Client == nil {
+		return "", errors.New("Redis Client is null")
+	}
+	return h.Client.HGet(ctx, h.key(key), field).Result()
+}
+
+func (h *RedisHelper) HSet(ctx context.Context, key string, fields map[string]string) (int64, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.HSet(ctx, h.key(key), fields).Result()
+}
+
+func (h *RedisHelper) HSetNX(ctx context.Context, key, field, val string) (bool, error) {
+	if h.Client == nil {
+		return false, errors.New("Redis Client is null")
+	}
+	return h.Client.HSetNX(ctx, h.key(key), field, val).Result()
+}
+
+func (h *RedisHelper) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if h.Client == nil {
+		return nil, errors.New("Redis Client is null")
+	}
+	return h.Client.HGetAll(ctx, h.key(key)).Result()
+}
+
+func (h *RedisHelper) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	if h.Client == nil {
+		return 0, errors.New("Redis Client is null")
+	}
+	return h.Client.HDel(ctx, h.key(key), fields...).Result()
+}
+
+// Scan matches keys inside the helper namespace and returns them without it.
+func (h *RedisHelper) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	if h.Client == nil {
+		return nil, 0, errors.New("Redis Client is null")
+	}
+	if len(match) == 0 {
+		match = "*"
+	}
+	keys, next, err := h.Client.Scan(ctx, cursor, h.Keys.Match(match), count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, k := range keys {
+		keys[i] = h.Keys.Trim(k)
+	}
+	return keys, next, nil
+}
+
+func (h *RedisHelper) Rename(ctx context.Context, key, newkey string) error {
+	if h.Client == nil {
+		return errors.New("Redis Client is null")
+	}
+	return h.Client.Rename(ctx, h.key(key), h.key(newkey)).Err()
+}
+
+func (h *RedisHelper) Type(ctx context.Context, key string) (string, error) {
+	if h.Client == nil {
+		return "", errors.New("Redis Client is null")
+	}
+	return h.Client.Type(ctx, h.key(key)).Result()
+}
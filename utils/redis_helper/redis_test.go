@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+func TestRedisHelper_GetKeysByPatternStaysInNamespace(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	mr.Set("shop:v1:a*:item:1", "1")
+	mr.Set("shop:v1:ab:item:2", "2")
+
+	h := NewRedisHelper(client).WithKeyBuilder(redisdb.NewKeyBuilder("shop", 1).Tenant("a*"))
+	keys, _, err := h.GetKeysByPattern("item:*")
+	require.Nil(t, err)
+	require.Equal(t, []string{"item:1"}, keys)
+}
+
+func TestRedisHelper_Store(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	h := NewRedisHelper(redis.NewClient(&redis.Options{Addr: mr.Addr()})).WithKeyBuilder(redisdb.NewKeyBuilder("shop", 1))
+
+	var store redisdb.Store = h
+	_, err := store.Set(ctx, "item:1", "a", 60)
+	require.Nil(t, err)
+	require.True(t, mr.Exists("shop:v1:item:1"))
+	val, err := store.Get(ctx, "item:1")
+	require.Nil(t, err)
+	require.Equal(t, "a", val)
+	_, err = store.Get(ctx, "missing")
+	require.Equal(t, redis.Nil, err)
+
+	require.Nil(t, store.Rename(ctx, "item:1", "item:2"))
+	keys, _, err := store.Scan(ctx, 0, "item:*", 100)
+	require.Nil(t, err)
+	require.Equal(t, []string{"item:2"}, keys)
+	typ, err := store.Type(ctx, "item:2")
+	require.Nil(t, err)
+	require.Equal(t, "string", typ)
+	vals, err := store.MGet(ctx, "item:2", "missing")
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"a", nil}, vals)
+
+	_, err = NewRedisHelper(nil).Get(ctx, "item:2")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,31 @@
+package redis
+
+import "strings"
+
+const slotNumber = 16384
+
+// KeySlot returns the redis cluster hash slot of key, honoring {hash tags}.
+func KeySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+e+1]
+		}
+	}
+	return int(crc16(key) % slotNumber)
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
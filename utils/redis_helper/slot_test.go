@@ -0,0 +1,14 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySlot(t *testing.T) {
+	require.Equal(t, 12739, KeySlot("123456789"))
+	require.Equal(t, 12182, KeySlot("foo"))
+	require.Equal(t, KeySlot("user1000"), KeySlot("{user1000}.following"))
+	require.NotEqual(t, KeySlot("bar"), KeySlot("{}bar"))
+}
@@ -0,0 +1,441 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/nats-io/nuid"
+)
+
+const (
+	defaultMaxAttempts  = 8
+	defaultBackoff      = time.Second
+	defaultBackoffMax   = time.Hour
+	defaultDisableAfter = 20
+	defaultWorkers      = 4
+	defaultQueueSize    = 1024
+	defaultHTTPTimeout  = 10 * time.Second
+	defaultInterval     = time.Second
+	defaultLease        = time.Minute
+	maxResponseBody     = 64 << 10
+)
+
+// ErrQueueFull is returned when the delivery queue is full.
+var ErrQueueFull = errors.New("webhook: delivery queue full")
+
+var _ transport.Server = (*Dispatcher)(nil)
+
+// Option is dispatcher option.
+type Option func(*Dispatcher)
+
+// WithHTTPClient sets the client sending the requests, its timeout bounds an attempt.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithRetry sets the attempts per delivery and the retry delay, it doubles per attempt up to max.
+func WithRetry(maxAttempts int, initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.backoffInitial = initial
+		d.backoffMax = max
+	}
+}
+
+// WithDisableAfter disables an endpoint after n consecutive failed attempts, default 20, 0 never disables.
+func WithDisableAfter(n int) Option {
+	return func(d *Dispatcher) {
+		d.disableAfter = n
+	}
+}
+
+// WithWorkers sets the number of concurrent deliveries.
+func WithWorkers(n int) Option {
+	return func(d *Dispatcher) {
+		d.workers = n
+	}
+}
+
+// WithQueueSize bounds the deliveries waiting for a worker.
+func WithQueueSize(n int) Option {
+	return func(d *Dispatcher) {
+		d.queueSize = n
+	}
+}
+
+// WithInterval sets how often due retries are polled.
+func WithInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.interval = interval
+	}
+}
+
+// WithLease sets how long a dispatcher owns the retries it polled, they are attempted
+// again by any dispatcher once it expired. It must exceed the http client timeout.
+func WithLease(lease time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.lease = lease
+	}
+}
+
+// WithLogger with dispatcher logger.
+func WithLogger(logger log.Logger) Option {
+	return func(d *Dispatcher) {
+		d.log = log.NewHelper(logger)
+	}
+}
+
+type delivery struct {
+	id         string
+	endpointID string
+	event      string
+	body       []byte
+	attempt    int
+	// retry is set on deliveries polled from the store
+	retry bool
+}
+
+// Dispatcher delivers events to the endpoints of a Store. It is a kratos
+// transport.Server, Start runs the workers and polls the due retries, so every pod can
+// run a dispatcher on the same store. Retries are saved in the store and survive a
+// restart, they are attempted at least once more and receivers dedupe with the
+// IDHeader. First attempts wait in memory and are dropped when the dispatcher stops;
+// use the outbox for events which must survive a restart.
+type Dispatcher struct {
+	store          Store
+	client         *http.Client
+	log            *log.Helper
+	maxAttempts    int
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	disableAfter   int
+	workers        int
+	queueSize      int
+	interval       time.Duration
+	lease          time.Duration
+
+	queue   chan *delivery
+	mu      sync.Mutex
+	running bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewDispatcher(store Store, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:          store,
+		client:         &http.Client{Timeout: defaultHTTPTimeout},
+		log:            log.NewHelper(log.GetLogger()),
+		maxAttempts:    defaultMaxAttempts,
+		backoffInitial: defaultBackoff,
+		backoffMax:     defaultBackoffMax,
+		disableAfter:   defaultDisableAfter,
+		workers:        defaultWorkers,
+		queueSize:      defaultQueueSize,
+		interval:       defaultInterval,
+		lease:          defaultLease,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.queue = make(chan *delivery, d.queueSize)
+	return d
+}
+
+// Register saves an endpoint, it needs an id, a url and a secret.
+func (d *Dispatcher) Register(ctx context.Context, e *Endpoint) error {
+	if len(e.ID) == 0 || len(e.URL) == 0 || len(e.Secrets) == 0 {
+		return errors.New("webhook: an endpoint needs an id, a url and a secret")
+	}
+	return d.store.SaveEndpoint(ctx, e)
+}
+
+// Enable enables a disabled endpoint and resets its failures.
+func (d *Dispatcher) Enable(ctx context.Context, id string) error {
+	return d.store.SetDisabled(ctx, id, false)
+}
+
+// Publish delivers payload to every enabled endpoint accepting event and returns the
+// delivery ids. payload is sent as is when it is []byte, json encoded otherwise. When
+// the queue fills up Publish returns ErrQueueFull with the ids queued so far, those
+// deliveries go on and the remaining endpoints get nothing.
+func (d *Dispatcher) Publish(ctx context.Context, event string, payload interface{}) ([]string, error) {
+	body, err := encode(payload)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := d.store.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range endpoints {
+		if e.Disabled || !e.Accepts(event) {
+			continue
+		}
+		id, err := d.enqueue(e.ID, event, body)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Send delivers payload to one endpoint and returns the delivery id.
+func (d *Dispatcher) Send(ctx context.Context, endpointID, event string, payload interface{}) (string, error) {
+	body, err := encode(payload)
+	if err != nil {
+		return "", err
+	}
+	e, err := d.store.Endpoint(ctx, endpointID)
+	if err != nil {
+		return "", err
+	}
+	if e.Disabled {
+		return "", fmt.Errorf("webhook: endpoint %s is disabled", endpointID)
+	}
+	return d.enqueue(endpointID, event, body)
+}
+
+// Attempts returns the attempts of a delivery.
+func (d *Dispatcher) Attempts(ctx context.Context, deliveryID string) ([]*Attempt, error) {
+	return d.store.Attempts(ctx, deliveryID)
+}
+
+func encode(payload interface{}) ([]byte, error) {
+	switch p := payload.(type) {
+	case []byte:
+		return p, nil
+	case json.RawMessage:
+		return p, nil
+	}
+	return json.Marshal(payload)
+}
+
+func (d *Dispatcher) enqueue(endpointID, event string, body []byte) (string, error) {
+	dl := &delivery{id: nuid.Next(), endpointID: endpointID, event: event, body: body}
+	select {
+	case d.queue <- dl:
+		return dl.id, nil
+	default:
+		return "", ErrQueueFull
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		return nil
+	}
+	d.running = true
+	d.stop = make(chan struct{})
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work(d.stop)
+	}
+	d.wg.Add(1)
+	go d.poll(d.stop)
+	d.log.Infof("[WEBHOOK] dispatcher started with %d workers", d.workers)
+	return nil
+}
+
+// Stop waits for the running attempts. Queued first attempts are dropped, queued
+// retries stay in the store and are polled again once their lease expired.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return nil
+	}
+	d.running = false
+	close(d.stop)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var dropped int
+	for len(d.queue) > 0 {
+		if dl := <-d.queue; !dl.retry {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		d.log.Warnw("msg", "webhook dispatcher stopped with pending deliveries", "dropped", dropped)
+	}
+	d.log.Info("[WEBHOOK] dispatcher stopped")
+	return nil
+}
+
+func (d *Dispatcher) work(stop chan struct{}) {
+	defer d.wg.Done()
+	for {
+		select {
+		case dl := <-d.queue:
+			d.deliver(context.Background(), dl)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// poll queues the due retries until stop is closed. A retry leased but not queued
+// when the dispatcher stops is polled again once its lease expired.
+func (d *Dispatcher) poll(stop chan struct{}) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		free := cap(d.queue) - len(d.queue)
+		if free == 0 {
+			continue
+		}
+		retries, err := d.store.DueRetries(context.Background(), time.Now().UTC(), d.lease, free)
+		if err != nil {
+			d.log.Errorw("msg", "webhook retry poll failed", "error", err)
+		}
+		for _, r := range retries {
+			dl := &delivery{id: r.DeliveryID, endpointID: r.EndpointID, event: r.Event, body: r.Body, attempt: r.Attempt, retry: true}
+			select {
+			case d.queue <- dl:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// deliver runs an attempt and saves the retry of a failed one.
+func (d *Dispatcher) deliver(ctx context.Context, dl *delivery) {
+	e, err := d.store.Endpoint(ctx, dl.endpointID)
+	if errors.Is(err, ErrNotFound) {
+		d.log.Warnw("msg", "webhook endpoint not found, delivery dropped", "endpoint", dl.endpointID, "delivery", dl.id)
+		d.done(ctx, dl)
+		return
+	}
+	if err != nil {
+		// a retry is polled again once its lease expired, a first attempt is saved as one
+		d.log.Errorw("msg", "webhook endpoint not loaded", "endpoint", dl.endpointID, "delivery", dl.id, "error", err)
+		if !dl.retry {
+			d.retry(ctx, dl)
+		}
+		return
+	}
+	if e.Disabled {
+		d.log.Warnw("msg", "webhook endpoint disabled, delivery dropped", "endpoint", e.ID, "delivery", dl.id)
+		d.done(ctx, dl)
+		return
+	}
+	dl.attempt++
+	a := d.post(ctx, e, dl)
+	failures, err := d.store.RecordAttempt(ctx, a)
+	if err != nil {
+		d.log.Errorw("msg", "webhook attempt not recorded", "endpoint", e.ID, "delivery", dl.id, "error", err)
+	}
+	if a.Success() {
+		d.done(ctx, dl)
+		return
+	}
+	if d.disableAfter > 0 && failures >= d.disableAfter {
+		if err := d.store.SetDisabled(ctx, e.ID, true); err != nil {
+			d.log.Errorw("msg", "webhook endpoint not disabled", "endpoint", e.ID, "error", err)
+		}
+		d.log.Warnw("msg", "webhook endpoint disabled", "endpoint", e.ID, "failures", failures)
+		d.done(ctx, dl)
+		return
+	}
+	if !a.Retryable() || dl.attempt >= d.maxAttempts {
+		d.log.Errorw("msg", "webhook delivery failed", "endpoint", e.ID, "delivery", dl.id, "event", dl.event,
+			"attempts", dl.attempt, "status", a.StatusCode, "error", a.Error)
+		d.done(ctx, dl)
+		return
+	}
+	d.retry(ctx, dl)
+}
+
+// retry saves the next attempt of a delivery.
+func (d *Dispatcher) retry(ctx context.Context, dl *delivery) {
+	r := &Retry{DeliveryID: dl.id, EndpointID: dl.endpointID, Event: dl.event, Body: dl.body,
+		Attempt: dl.attempt, Due: time.Now().UTC().Add(d.backoff(dl.attempt))}
+	if err := d.store.ScheduleRetry(ctx, r); err != nil {
+		d.log.Errorw("msg", "webhook retry not saved", "endpoint", dl.endpointID, "delivery", dl.id, "error", err)
+	}
+}
+
+// done forgets the saved retry of a delivery.
+func (d *Dispatcher) done(ctx context.Context, dl *delivery) {
+	if !dl.retry {
+		return
+	}
+	if err := d.store.DeleteRetry(ctx, dl.id); err != nil {
+		d.log.Errorw("msg", "webhook retry not deleted", "endpoint", dl.endpointID, "delivery", dl.id, "error", err)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, e *Endpoint, dl *delivery) *Attempt {
+	now := time.Now().UTC()
+	a := &Attempt{DeliveryID: dl.id, EndpointID: e.ID, Event: dl.event, Attempt: dl.attempt, CreatedAt: now}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, dl.id)
+	req.Header.Set(EventHeader, dl.event)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(e.Secrets, dl.id, now, dl.body))
+
+	resp, err := d.client.Do(req)
+	a.Duration = time.Since(now)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	a.StatusCode = resp.StatusCode
+	return a
+}
+
+// backoff doubles the delay per attempt up to the max, the delay is jittered between half and all of it.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.backoffInitial
+	for i := 1; i < attempt && delay < d.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > d.backoffMax {
+		delay = d.backoffMax
+	}
+	if half := int64(delay) / 2; half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
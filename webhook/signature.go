@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a webhook request.
+const (
+	IDHeader        = "Webhook-Id"
+	EventHeader     = "Webhook-Event"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+)
+
+const signatureVersion = "v1"
+
+var (
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrStaleTimestamp   = errors.New("webhook: timestamp outside tolerance")
+)
+
+// Sign returns the signature header of body, t=<unix>,v1=<hex>[,v1=<hex>]. Every secret
+// adds a v1 signature, so during a key rotation receivers verify with either key.
+// The signed content is <id>.<unix timestamp>.<body> hashed with HMAC-SHA256.
+func Sign(secrets []string, id string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, "t="+ts)
+	for _, secret := range secrets {
+		parts = append(parts, signatureVersion+"="+hex.EncodeToString(mac(secret, id, ts, body)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify checks the signature header of body against any of secrets and rejects
+// timestamps more than tolerance away from now, a zero tolerance skips the check.
+func Verify(secrets []string, id, header string, body []byte, tolerance time.Duration) error {
+	ts, signatures := parse(header)
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if d := time.Since(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+			return ErrStaleTimestamp
+		}
+	}
+	for _, secret := range secrets {
+		expected := mac(secret, id, ts, body)
+		for _, sig := range signatures {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Timestamp returns the signed timestamp of a signature header, retries of a delivery
+// keep its id and are signed again with a new timestamp.
+func Timestamp(header string) (time.Time, error) {
+	ts, _ := parse(header)
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidSignature
+	}
+	return time.Unix(unix, 0), nil
+}
+
+func parse(header string) (string, [][]byte) {
+	var ts string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case signatureVersion:
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	return ts, signatures
+}
+
+func mac(secret, id, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(id))
+	h.Write([]byte("."))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/sqldb"
+)
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLOption is sql store option.
+type SQLOption func(*SQLStore)
+
+// WithTables sets the endpoint, attempt and retry table names, default webhook_endpoints,
+// webhook_attempts and webhook_retries.
+func WithTables(endpoints, attempts, retries string) SQLOption {
+	return func(s *SQLStore) {
+		s.endpoints = endpoints
+		s.attempts = attempts
+		s.retries = retries
+	}
+}
+
+// SQLStore keeps endpoints, attempts and retries in the following tables (PostgreSQL shown):
+//
+//	CREATE TABLE webhook_endpoints (
+//		id         VARCHAR(64) PRIMARY KEY,
+//		url        TEXT NOT NULL,
+//		secrets    TEXT NOT NULL,
+//		events     TEXT NOT NULL,
+//		disabled   BOOLEAN NOT NULL,
+//		failures   INT NOT NULL,
+//		updated_at TIMESTAMP NOT NULL
+//	);
+//	CREATE TABLE webhook_attempts (
+//		delivery_id VARCHAR(64) NOT NULL,
+//		attempt     INT NOT NULL,
+//		endpoint_id VARCHAR(64) NOT NULL,
+//		event       VARCHAR(255) NOT NULL,
+//		status_code INT NOT NULL,
+//		error       TEXT NOT NULL,
+//		duration_ms BIGINT NOT NULL,
+//		created_at  TIMESTAMP NOT NULL,
+//		PRIMARY KEY (delivery_id, attempt)
+//	);
+//	CREATE TABLE webhook_retries (
+//		delivery_id VARCHAR(64) PRIMARY KEY,
+//		endpoint_id VARCHAR(64) NOT NULL,
+//		event       VARCHAR(255) NOT NULL,
+//		body        BYTEA NOT NULL,
+//		attempt     INT NOT NULL,
+//		due_at      TIMESTAMP NOT NULL
+//	);
+//	CREATE INDEX webhook_retries_due ON webhook_retries (due_at);
+type SQLStore struct {
+	db        *sql.DB
+	postgres  bool
+	endpoints string
+	attempts  string
+	retries   string
+}
+
+// NewSQLStore opens the configured database.
+func NewSQLStore(c *conf.Database, opts ...SQLOption) (*SQLStore, error) {
+	db, err := sqldb.Open(c)
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLStoreWithDB(db, c.Driver, opts...), nil
+}
+
+// NewSQLStoreWithDB uses a database handle owned by the caller, driver selects the placeholder style.
+func NewSQLStoreWithDB(db *sql.DB, driver string, opts ...SQLOption) *SQLStore {
+	s := &SQLStore{
+		db:        db,
+		postgres:  sqldb.IsPostgres(driver),
+		endpoints: "webhook_endpoints",
+		attempts:  "webhook_attempts",
+		retries:   "webhook_retries",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *SQLStore) rebind(query string) string {
+	if !s.postgres {
+		return query
+	}
+	return sqldb.Rebind(query)
+}
+
+// SaveEndpoint updates the endpoint or inserts it, the failures are kept.
+func (s *SQLStore) SaveEndpoint(ctx context.Context, e *Endpoint) error {
+	secrets, err := json.Marshal(e.Secrets)
+	if err != nil {
+		return err
+	}
+	events, err := json.Marshal(e.Events)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	query := "UPDATE " + s.endpoints + " SET url = ?, secrets = ?, events = ?, disabled = ?, updated_at = ? WHERE id = ?"
+	res, err := s.db.ExecContext(ctx, s.rebind(query), e.URL, string(secrets), string(events), e.Disabled, now, e.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	query = "INSERT INTO " + s.endpoints + " (id, url, secrets, events, disabled, failures, updated_at) VALUES (?, ?, ?, ?, ?, 0, ?)"
+	_, err = s.db.ExecContext(ctx, s.rebind(query), e.ID, e.URL, string(secrets), string(events), e.Disabled, now)
+	return err
+}
+
+const endpointColumns = "id, url, secrets, events, disabled, failures"
+
+func (s *SQLStore) Endpoint(ctx context.Context, id string) (*Endpoint, error) {
+	query := "SELECT " + endpointColumns + " FROM " + s.endpoints + " WHERE id = ?"
+	e, err := scanEndpoint(s.db.QueryRowContext(ctx, s.rebind(query), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+func (s *SQLStore) Endpoints(ctx context.Context) ([]*Endpoint, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+endpointColumns+" FROM "+s.endpoints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var endpoints []*Endpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEndpoint(row scanner) (*Endpoint, error) {
+	e := &Endpoint{}
+	var secrets, events string
+	if err := row.Scan(&e.ID, &e.URL, &secrets, &events, &e.Disabled, &e.Failures); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(secrets), &e.Secrets); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &e.Events); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *SQLStore) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	query := "UPDATE " + s.endpoints + " SET disabled = ?, updated_at = ? WHERE id = ?"
+	if !disabled {
+		query = "UPDATE " + s.endpoints + " SET disabled = ?, failures = 0, updated_at = ? WHERE id = ?"
+	}
+	res, err := s.db.ExecContext(ctx, s.rebind(query), disabled, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *SQLStore) RecordAttempt(ctx context.Context, a *Attempt) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := "INSERT INTO " + s.attempts + " (delivery_id, attempt, endpoint_id, event, status_code, error, duration_ms, created_at)" +
+		" VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, s.rebind(query), a.DeliveryID, a.Attempt, a.EndpointID, a.Event, a.StatusCode,
+		a.Error, a.Duration.Milliseconds(), a.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	// a permanent failure leaves the count of retryable failures
+	if a.Success() || a.Retryable() {
+		query = "UPDATE " + s.endpoints + " SET failures = failures + 1 WHERE id = ?"
+		if a.Success() {
+			query = "UPDATE " + s.endpoints + " SET failures = 0 WHERE id = ?"
+		}
+		if _, err := tx.ExecContext(ctx, s.rebind(query), a.EndpointID); err != nil {
+			return 0, err
+		}
+	}
+	var failures int
+	query = "SELECT failures FROM " + s.endpoints + " WHERE id = ?"
+	if err := tx.QueryRowContext(ctx, s.rebind(query), a.EndpointID).Scan(&failures); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return failures, tx.Commit()
+}
+
+func (s *SQLStore) Attempts(ctx context.Context, deliveryID string) ([]*Attempt, error) {
+	query := "SELECT delivery_id, attempt, endpoint_id, event, status_code, error, duration_ms, created_at FROM " + s.attempts +
+		" WHERE delivery_id = ? ORDER BY attempt"
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attempts []*Attempt
+	for rows.Next() {
+		a := &Attempt{}
+		var ms int64
+		if err := rows.Scan(&a.DeliveryID, &a.Attempt, &a.EndpointID, &a.Event, &a.StatusCode, &a.Error, &ms, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// ScheduleRetry updates the retry of the delivery or inserts it.
+func (s *SQLStore) ScheduleRetry(ctx context.Context, r *Retry) error {
+	query := "UPDATE " + s.retries + " SET attempt = ?, due_at = ? WHERE delivery_id = ?"
+	res, err := s.db.ExecContext(ctx, s.rebind(query), r.Attempt, r.Due, r.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	query = "INSERT INTO " + s.retries + " (delivery_id, endpoint_id, event, body, attempt, due_at) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err = s.db.ExecContext(ctx, s.rebind(query), r.DeliveryID, r.EndpointID, r.Event, r.Body, r.Attempt, r.Due)
+	return err
+}
+
+// DueRetries locks the due rows with FOR UPDATE SKIP LOCKED and moves their due time
+// lease later in the same transaction.
+func (s *SQLStore) DueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Retry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := "SELECT delivery_id, endpoint_id, event, body, attempt, due_at FROM " + s.retries +
+		" WHERE due_at <= ? ORDER BY due_at LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.QueryContext(ctx, s.rebind(query), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var retries []*Retry
+	for rows.Next() {
+		r := &Retry{}
+		if err := rows.Scan(&r.DeliveryID, &r.EndpointID, &r.Event, &r.Body, &r.Attempt, &r.Due); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		retries = append(retries, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(retries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]interface{}, 0, len(retries)+1)
+	ids = append(ids, now.Add(lease))
+	for _, r := range retries {
+		ids = append(ids, r.DeliveryID)
+	}
+	query = "UPDATE " + s.retries + " SET due_at = ? WHERE delivery_id IN (" + placeholders(len(retries)) + ")"
+	if _, err := tx.ExecContext(ctx, s.rebind(query), ids...); err != nil {
+		return nil, err
+	}
+	return retries, tx.Commit()
+}
+
+func (s *SQLStore) DeleteRetry(ctx context.Context, deliveryID string) error {
+	query := "DELETE FROM " + s.retries + " WHERE delivery_id = ?"
+	_, err := s.db.ExecContext(ctx, s.rebind(query), deliveryID)
+	return err
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStore_RecordAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	store := NewSQLStoreWithDB(db, "pgx")
+	now := time.Now().UTC()
+	insert := `INSERT INTO webhook_attempts`
+	failures := `SELECT failures FROM webhook_endpoints WHERE id = \$1`
+
+	// a retryable failure is counted
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WithArgs("d1", 1, "partner", "order.created", http.StatusBadGateway, "", int64(0), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE webhook_endpoints SET failures = failures \+ 1 WHERE id = \$1`).WithArgs("partner").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(failures).WithArgs("partner").WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+	mock.ExpectCommit()
+	n, err := store.RecordAttempt(context.Background(), &Attempt{DeliveryID: "d1", EndpointID: "partner",
+		Event: "order.created", Attempt: 1, StatusCode: http.StatusBadGateway, CreatedAt: now})
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+
+	// a permanent failure is not
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(failures).WithArgs("partner").WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+	mock.ExpectCommit()
+	n, err = store.RecordAttempt(context.Background(), &Attempt{DeliveryID: "d2", EndpointID: "partner",
+		Event: "order.created", Attempt: 1, StatusCode: http.StatusBadRequest, CreatedAt: now})
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE webhook_endpoints SET failures = 0 WHERE id = \$1`).WithArgs("partner").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(failures).WithArgs("partner").WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(0))
+	mock.ExpectCommit()
+	n, err = store.RecordAttempt(context.Background(), &Attempt{DeliveryID: "d3", EndpointID: "partner",
+		Event: "order.created", Attempt: 1, StatusCode: http.StatusNoContent, CreatedAt: now})
+	require.Nil(t, err)
+	require.Zero(t, n)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLStore_Retries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer db.Close()
+	store := NewSQLStoreWithDB(db, "pgx")
+	now := time.Now().UTC()
+	r := &Retry{DeliveryID: "d1", EndpointID: "partner", Event: "order.created", Body: []byte(`{}`), Attempt: 1, Due: now}
+
+	mock.ExpectExec(`UPDATE webhook_retries SET attempt = \$1, due_at = \$2 WHERE delivery_id = \$3`).
+		WithArgs(1, now, "d1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO webhook_retries`).WithArgs("d1", "partner", "order.created", []byte(`{}`), 1, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.Nil(t, store.ScheduleRetry(context.Background(), r))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT .* FROM webhook_retries WHERE due_at <= \$1 ORDER BY due_at LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "endpoint_id", "event", "body", "attempt", "due_at"}).
+			AddRow("d1", "partner", "order.created", []byte(`{}`), 1, now))
+	mock.ExpectExec(`UPDATE webhook_retries SET due_at = \$1 WHERE delivery_id IN \(\$2\)`).
+		WithArgs(now.Add(time.Minute), "d1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	retries, err := store.DueRetries(context.Background(), now, time.Minute, 10)
+	require.Nil(t, err)
+	require.Equal(t, []*Retry{r}, retries)
+
+	mock.ExpectExec(`DELETE FROM webhook_retries WHERE delivery_id = \$1`).WithArgs("d1").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.Nil(t, store.DeleteRetry(context.Background(), "d1"))
+	require.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,359 @@
+// Package webhook delivers events to partner endpoints over HTTP. Requests are signed
+// with HMAC-SHA256 (see Sign), failed deliveries are retried with exponential backoff
+// and jitter, every attempt and pending retry is persisted in a Store and endpoints
+// failing repeatedly are disabled.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/nartvt/go-core/conf"
+	"github.com/nartvt/go-core/database/redisdb"
+	"github.com/nartvt/go-core/pubsub"
+)
+
+var ErrNotFound = errors.New("webhook: endpoint not found")
+
+// Endpoint is a partner url. Secrets sign the requests, the first one is the current
+// secret and the others are kept during a rotation. Events are the event patterns the
+// endpoint receives with the NATS wildcards, empty means every event.
+type Endpoint struct {
+	ID       string   `json:"id"`
+	URL      string   `json:"url"`
+	Secrets  []string `json:"secrets"`
+	Events   []string `json:"events,omitempty"`
+	Disabled bool     `json:"disabled"`
+	// Failures is the number of consecutive retryable failed attempts.
+	Failures int `json:"failures"`
+}
+
+// Accepts reports whether the endpoint receives event.
+func (e *Endpoint) Accepts(event string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, pattern := range e.Events {
+		if pubsub.MatchSubject(pattern, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt is a delivery attempt, StatusCode is 0 when no response was received.
+type Attempt struct {
+	DeliveryID string        `json:"delivery_id"`
+	EndpointID string        `json:"endpoint_id"`
+	Event      string        `json:"event"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Success reports whether the endpoint accepted the request.
+func (a *Attempt) Success() bool {
+	return len(a.Error) == 0 && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Retryable reports whether a failed attempt may succeed later: no response, a server
+// error, a timeout or a rate limit. Other client errors are permanent.
+func (a *Attempt) Retryable() bool {
+	switch {
+	case a.StatusCode == 0, a.StatusCode >= 500:
+		return true
+	case a.StatusCode == http.StatusTooManyRequests, a.StatusCode == http.StatusRequestTimeout:
+		return true
+	}
+	return false
+}
+
+// Retry is a delivery waiting for its next attempt, Attempt is the number of attempts made.
+type Retry struct {
+	DeliveryID string    `json:"delivery_id"`
+	EndpointID string    `json:"endpoint_id"`
+	Event      string    `json:"event"`
+	Body       []byte    `json:"body"`
+	Attempt    int       `json:"attempt"`
+	Due        time.Time `json:"due"`
+}
+
+// Store persists endpoints, attempts and pending retries.
+type Store interface {
+	SaveEndpoint(ctx context.Context, e *Endpoint) error
+	Endpoint(ctx context.Context, id string) (*Endpoint, error)
+	Endpoints(ctx context.Context) ([]*Endpoint, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) error
+	// RecordAttempt stores a and returns the consecutive retryable failures of its
+	// endpoint, a successful attempt resets them and a permanent failure leaves them.
+	RecordAttempt(ctx context.Context, a *Attempt) (int, error)
+	// Attempts returns the attempts of a delivery in order.
+	Attempts(ctx context.Context, deliveryID string) ([]*Attempt, error)
+	// ScheduleRetry saves r, replacing the retry of the same delivery.
+	ScheduleRetry(ctx context.Context, r *Retry) error
+	// DueRetries leases up to limit retries due at now by moving them lease later, so
+	// the retries of a dispatcher which stopped are returned again once it expired.
+	DueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Retry, error)
+	// DeleteRetry forgets the retry of a delivery.
+	DeleteRetry(ctx context.Context, deliveryID string) error
+}
+
+const defaultRetention = 7 * 24 * time.Hour
+
+// scheduleRetryScript saves the retry, indexes its due time and releases its lease.
+var scheduleRetryScript = redis.NewScript(`
+redis.call('HSET', KEYS[3], ARGV[1], ARGV[3])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// leaseRetriesScript moves the retries of expired leases back to the due set, then
+// leases up to ARGV[3] retries due at ARGV[1] until ARGV[2] and returns them.
+var leaseRetriesScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	if redis.call('ZSCORE', KEYS[1], id) == false then
+		redis.call('ZADD', KEYS[1], ARGV[1], id)
+	end
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+local result = {}
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local payload = redis.call('HGET', KEYS[3], id)
+	if payload then
+		redis.call('ZADD', KEYS[2], ARGV[2], id)
+		table.insert(result, payload)
+	end
+end
+return result
+`)
+
+// deleteRetryScript forgets a retry, leased or not.
+var deleteRetryScript = redis.NewScript(`
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+// RedisOption is redis store option.
+type RedisOption func(*RedisStore)
+
+// WithRetention sets how long the attempts of a delivery are kept, default 7 days.
+func WithRetention(d time.Duration) RedisOption {
+	return func(s *RedisStore) {
+		s.retention = d
+	}
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore keeps the endpoints in the hash webhook:endpoints, their failures in
+// webhook:failures:<id> and the attempts of a delivery in the hash
+// webhook:attempts:<id>. Pending retries are kept in the hash {webhook}:retries with
+// their due times in the sorted set {webhook}:due, polled retries are moved to the
+// sorted set {webhook}:leased until their lease expires.
+type RedisStore struct {
+	client    *redisdb.RedisClient
+	owned     bool
+	retention time.Duration
+}
+
+// NewRedisStore connects to redis, the connection is closed by Close.
+func NewRedisStore(c *conf.Redis, opts ...RedisOption) *RedisStore {
+	s := NewRedisStoreWithClient(redisdb.NewRedisClient(c), opts...)
+	s.owned = true
+	return s
+}
+
+// NewRedisStoreWithClient uses a client owned by the caller.
+func NewRedisStoreWithClient(client *redisdb.RedisClient, opts ...RedisOption) *RedisStore {
+	s := &RedisStore{client: client, retention: defaultRetention}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RedisStore) endpointsKey() string {
+	return s.client.KeyBuilder().Key("webhook", "endpoints")
+}
+
+func (s *RedisStore) failuresKey(id string) string {
+	return s.client.KeyBuilder().Key("webhook", "failures", id)
+}
+
+func (s *RedisStore) attemptsKey(deliveryID string) string {
+	return s.client.KeyBuilder().Key("webhook", "attempts", deliveryID)
+}
+
+// retryKeys returns the due set, the lease set and the retry hash, they share a hash
+// tag to live on the same cluster slot.
+func (s *RedisStore) retryKeys() []string {
+	keys := s.client.KeyBuilder()
+	return []string{
+		keys.Tagged("webhook", "due"),
+		keys.Tagged("webhook", "leased"),
+		keys.Tagged("webhook", "retries"),
+	}
+}
+
+func (s *RedisStore) SaveEndpoint(ctx context.Context, e *Endpoint) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.client.GetClient().HSet(ctx, s.endpointsKey(), e.ID, b).Err()
+}
+
+func (s *RedisStore) Endpoint(ctx context.Context, id string) (*Endpoint, error) {
+	val, err := s.client.GetClient().HGet(ctx, s.endpointsKey(), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(ctx, val)
+}
+
+func (s *RedisStore) Endpoints(ctx context.Context) ([]*Endpoint, error) {
+	all, err := s.client.GetClient().HGetAll(ctx, s.endpointsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]*Endpoint, 0, len(all))
+	for _, val := range all {
+		e, err := s.decode(ctx, val)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+func (s *RedisStore) decode(ctx context.Context, val string) (*Endpoint, error) {
+	e := &Endpoint{}
+	if err := json.Unmarshal([]byte(val), e); err != nil {
+		return nil, err
+	}
+	failures, err := s.client.GetClient().Get(ctx, s.failuresKey(e.ID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	e.Failures, _ = strconv.Atoi(failures)
+	return e, nil
+}
+
+func (s *RedisStore) SetDisabled(ctx context.Context, id string, disabled bool) error {
+	e, err := s.Endpoint(ctx, id)
+	if err != nil {
+		return err
+	}
+	e.Disabled = disabled
+	if !disabled {
+		if err := s.client.GetClient().Del(ctx, s.failuresKey(id)).Err(); err != nil {
+			return err
+		}
+	}
+	return s.SaveEndpoint(ctx, e)
+}
+
+func (s *RedisStore) RecordAttempt(ctx context.Context, a *Attempt) (int, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return 0, err
+	}
+	rdb := s.client.GetClient()
+	key := s.attemptsKey(a.DeliveryID)
+	if err := rdb.HSet(ctx, key, strconv.Itoa(a.Attempt), b).Err(); err != nil {
+		return 0, err
+	}
+	if err := rdb.Expire(ctx, key, s.retention).Err(); err != nil {
+		return 0, err
+	}
+	if a.Success() {
+		return 0, rdb.Del(ctx, s.failuresKey(a.EndpointID)).Err()
+	}
+	if !a.Retryable() {
+		failures, err := rdb.Get(ctx, s.failuresKey(a.EndpointID)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return 0, err
+		}
+		n, _ := strconv.Atoi(failures)
+		return n, nil
+	}
+	failures, err := rdb.Incr(ctx, s.failuresKey(a.EndpointID)).Result()
+	return int(failures), err
+}
+
+func (s *RedisStore) Attempts(ctx context.Context, deliveryID string) ([]*Attempt, error) {
+	all, err := s.client.GetClient().HGetAll(ctx, s.attemptsKey(deliveryID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	attempts := make([]*Attempt, len(all))
+	for field, val := range all {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(all) {
+			return nil, fmt.Errorf("webhook: invalid attempt %q of %s", field, deliveryID)
+		}
+		a := &Attempt{}
+		if err := json.Unmarshal([]byte(val), a); err != nil {
+			return nil, err
+		}
+		attempts[n-1] = a
+	}
+	return attempts, nil
+}
+
+// ScheduleRetry saves r with its due time and releases its lease.
+func (s *RedisStore) ScheduleRetry(ctx context.Context, r *Retry) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return scheduleRetryScript.Run(ctx, s.client.GetClient(), s.retryKeys(), r.DeliveryID, r.Due.UnixMilli(), b).Err()
+}
+
+func (s *RedisStore) DueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Retry, error) {
+	payloads, err := leaseRetriesScript.Run(ctx, s.client.GetClient(), s.retryKeys(),
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	retries := make([]*Retry, 0, len(payloads))
+	for _, payload := range payloads {
+		r := &Retry{}
+		if err := json.Unmarshal([]byte(payload), r); err != nil {
+			return retries, err
+		}
+		retries = append(retries, r)
+	}
+	return retries, nil
+}
+
+func (s *RedisStore) DeleteRetry(ctx context.Context, deliveryID string) error {
+	return deleteRetryScript.Run(ctx, s.client.GetClient(), s.retryKeys(), deliveryID).Err()
+}
+
+// Close closes the connection when the store opened it.
+func (s *RedisStore) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.client.GetClient().Close()
+}
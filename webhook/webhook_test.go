@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nartvt/go-core/database/redisdb"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+
+	header := Sign([]string{"new", "old"}, "d1", now, body)
+	require.Nil(t, Verify([]string{"old"}, "d1", header, body, time.Minute))
+	require.Nil(t, Verify([]string{"new"}, "d1", header, body, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify([]string{"other"}, "d1", header, body, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify([]string{"new"}, "d2", header, body, time.Minute))
+	require.Equal(t, ErrInvalidSignature, Verify([]string{"new"}, "d1", header, []byte(`{"id":2}`), time.Minute))
+
+	stale := Sign([]string{"new"}, "d1", now.Add(-time.Hour), body)
+	require.Equal(t, ErrStaleTimestamp, Verify([]string{"new"}, "d1", stale, body, time.Minute))
+}
+
+func newStore(t *testing.T) *RedisStore {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStoreWithClient(redisdb.WrapClient(client))
+}
+
+func newDispatcher(t *testing.T, opts ...Option) *Dispatcher {
+	return startDispatcher(t, newStore(t), opts...)
+}
+
+func startDispatcher(t *testing.T, store Store, opts ...Option) *Dispatcher {
+	opts = append([]Option{WithRetry(3, time.Millisecond, 5*time.Millisecond), WithInterval(time.Millisecond)}, opts...)
+	d := NewDispatcher(store, opts...)
+	require.Nil(t, d.Start(context.Background()))
+	t.Cleanup(func() {
+		require.Nil(t, d.Stop(context.Background()))
+	})
+	return d
+}
+
+func TestDispatcher_RetryUntilDelivered(t *testing.T) {
+	var calls int32
+	// the handler runs in the server goroutine, its checks are asserted by the test
+	errs := make(chan error, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := Verify([]string{"secret"}, r.Header.Get(IDHeader), r.Header.Get(SignatureHeader), body, time.Minute)
+		if err == nil && r.Header.Get(EventHeader) != "order.created" {
+			err = fmt.Errorf("unexpected event %q", r.Header.Get(EventHeader))
+		}
+		errs <- err
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	d := newDispatcher(t)
+	require.Nil(t, d.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}, Events: []string{"order.*"}}))
+
+	ids, err := d.Publish(ctx, "order.created", map[string]int{"id": 1})
+	require.Nil(t, err)
+	require.Len(t, ids, 1)
+	none, err := d.Publish(ctx, "user.created", map[string]int{"id": 1})
+	require.Nil(t, err)
+	require.Empty(t, none)
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 3 }, time.Second, time.Millisecond)
+	var attempts []*Attempt
+	require.Eventually(t, func() bool {
+		attempts, err = d.Attempts(ctx, ids[0])
+		return err == nil && len(attempts) == 3
+	}, time.Second, time.Millisecond)
+	require.Equal(t, http.StatusServiceUnavailable, attempts[0].StatusCode)
+	require.True(t, attempts[2].Success())
+	for i := 0; i < 3; i++ {
+		require.Nil(t, <-errs)
+	}
+}
+
+func TestDispatcher_DisableAfterFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	d := newDispatcher(t, WithDisableAfter(2))
+	require.Nil(t, d.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}}))
+
+	_, err := d.Send(ctx, "partner", "order.created", []byte(`{}`))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		e, err := d.store.Endpoint(ctx, "partner")
+		return err == nil && e.Disabled
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	_, err = d.Send(ctx, "partner", "order.created", []byte(`{}`))
+	require.NotNil(t, err)
+
+	require.Nil(t, d.Enable(ctx, "partner"))
+	e, err := d.store.Endpoint(ctx, "partner")
+	require.Nil(t, err)
+	require.False(t, e.Disabled)
+	require.Zero(t, e.Failures)
+}
+
+func TestDispatcher_NoRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	d := newDispatcher(t)
+	require.Nil(t, d.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}}))
+	id, err := d.Send(ctx, "partner", "order.created", []byte(`{}`))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		attempts, err := d.Attempts(ctx, id)
+		return err == nil && len(attempts) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestDispatcher_PermanentFailuresDoNotDisable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	d := newDispatcher(t, WithDisableAfter(2))
+	require.Nil(t, d.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}}))
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := d.Send(ctx, "partner", "order.created", []byte(`{}`))
+		require.Nil(t, err)
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		require.Eventually(t, func() bool {
+			attempts, err := d.Attempts(ctx, id)
+			return err == nil && len(attempts) == 1
+		}, time.Second, time.Millisecond)
+	}
+	e, err := d.store.Endpoint(ctx, "partner")
+	require.Nil(t, err)
+	require.False(t, e.Disabled)
+	require.Zero(t, e.Failures)
+}
+
+func TestDispatcher_RetriesSurviveStop(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := newStore(t)
+	// the first dispatcher never polls its retry
+	first := NewDispatcher(store, WithInterval(time.Hour), WithRetry(3, 20*time.Millisecond, 20*time.Millisecond))
+	require.Nil(t, first.Start(ctx))
+	require.Nil(t, first.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}}))
+	id, err := first.Send(ctx, "partner", "order.created", []byte(`{}`))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		attempts, err := first.Attempts(ctx, id)
+		return err == nil && len(attempts) == 1
+	}, time.Second, time.Millisecond)
+	require.Nil(t, first.Stop(ctx))
+
+	second := startDispatcher(t, store)
+	require.Eventually(t, func() bool {
+		attempts, err := second.Attempts(ctx, id)
+		return err == nil && len(attempts) == 2 && attempts[1].Success()
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		retries, err := store.DueRetries(ctx, time.Now().Add(time.Hour), time.Minute, 10)
+		return err == nil && len(retries) == 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRedisStore_LeasesDueRetries(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	now := time.Now().UTC()
+	require.Nil(t, store.ScheduleRetry(ctx, &Retry{DeliveryID: "d1", EndpointID: "partner", Attempt: 1, Due: now.Add(-time.Second)}))
+	require.Nil(t, store.ScheduleRetry(ctx, &Retry{DeliveryID: "d2", EndpointID: "partner", Attempt: 1, Due: now.Add(time.Hour)}))
+
+	retries, err := store.DueRetries(ctx, now, time.Minute, 10)
+	require.Nil(t, err)
+	require.Len(t, retries, 1)
+	require.Equal(t, "d1", retries[0].DeliveryID)
+	retries, err = store.DueRetries(ctx, now, time.Minute, 10)
+	require.Nil(t, err)
+	require.Empty(t, retries, "d1 is leased")
+
+	// rescheduling releases the lease
+	require.Nil(t, store.ScheduleRetry(ctx, &Retry{DeliveryID: "d1", EndpointID: "partner", Attempt: 2, Due: now}))
+	retries, err = store.DueRetries(ctx, now, time.Minute, 10)
+	require.Nil(t, err)
+	require.Len(t, retries, 1)
+	require.Equal(t, 2, retries[0].Attempt)
+	// an expired lease is polled again
+	retries, err = store.DueRetries(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.Nil(t, err)
+	require.Len(t, retries, 1)
+	require.Equal(t, "d1", retries[0].DeliveryID)
+
+	require.Nil(t, store.DeleteRetry(ctx, "d1"))
+	retries, err = store.DueRetries(ctx, now.Add(2*time.Hour), time.Minute, 10)
+	require.Nil(t, err)
+	require.Len(t, retries, 1)
+	require.Equal(t, "d2", retries[0].DeliveryID)
+}
+
+// unavailableStore fails the endpoint lookup listed in fail, counted from 1.
+type unavailableStore struct {
+	Store
+	calls int32
+	fail  int32
+}
+
+func (s *unavailableStore) Endpoint(ctx context.Context, id string) (*Endpoint, error) {
+	if atomic.AddInt32(&s.calls, 1) == s.fail {
+		return nil, errors.New("store unavailable")
+	}
+	return s.Store.Endpoint(ctx, id)
+}
+
+func TestDispatcher_RetriesFirstAttemptOnStoreError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	// Send loads the endpoint first, the worker lookup fails
+	d := startDispatcher(t, &unavailableStore{Store: newStore(t), fail: 2})
+	require.Nil(t, d.Register(ctx, &Endpoint{ID: "partner", URL: server.URL, Secrets: []string{"secret"}}))
+	id, err := d.Send(ctx, "partner", "order.created", []byte(`{}`))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		attempts, err := d.Attempts(ctx, id)
+		return err == nil && len(attempts) == 1 && attempts[0].Success()
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRedisStore_KeysFollowTheNamespace(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisStoreWithClient(redisdb.WrapClient(client).WithKeyBuilder(redisdb.NewKeyBuilder("shop", 1)))
+
+	require.Nil(t, store.SaveEndpoint(ctx, &Endpoint{ID: "partner", URL: "http://partner", Secrets: []string{"secret"}}))
+	require.Nil(t, store.ScheduleRetry(ctx, &Retry{DeliveryID: "d1", EndpointID: "partner", Attempt: 1, Due: time.Now()}))
+	require.ElementsMatch(t, []string{"shop:v1:webhook:endpoints", "shop:v1:{webhook}:due", "shop:v1:{webhook}:retries"}, mr.Keys())
+}